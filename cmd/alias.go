@@ -0,0 +1,102 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/config"
+)
+
+// aliasCmd represents the alias command
+var aliasCmd = &cobra.Command{
+	Use:   "alias",
+	Short: "Manages context and namespace aliases",
+	Long: `Manages aliases in the config file, which map a short name to a context
+and optional namespace:
+
+  aliases:
+    prod:
+      context: gke_acme_us-east1_prod
+      namespace: payments`,
+}
+
+var aliasListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "Lists aliases",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		names := make([]string, 0, len(cfg.Aliases))
+		for k := range cfg.Aliases {
+			names = append(names, k)
+		}
+		sort.Strings(names)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ALIAS\tCONTEXT\tNAMESPACE")
+		for _, v := range names {
+			alias := cfg.Aliases[v]
+			fmt.Fprintf(w, "%s\t%s\t%s\n", v, alias.Context, alias.Namespace)
+		}
+		w.Flush()
+	},
+}
+
+var aliasAddCmd = &cobra.Command{
+	Use:   "add <alias> <context> [namespace]",
+	Short: "Adds or replaces an alias",
+	Args:  cobra.RangeArgs(2, 3),
+	Run: func(cmd *cobra.Command, args []string) {
+		alias := config.Alias{Context: args[1]}
+		if len(args) > 2 {
+			alias.Namespace = args[2]
+		}
+
+		if err := config.SetAlias(configPath(), args[0], alias); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var aliasRmCmd = &cobra.Command{
+	Use:   "rm <alias>",
+	Short: "Removes an alias",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := config.RemoveAlias(configPath(), args[0]); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(aliasCmd)
+	aliasCmd.AddCommand(aliasListCmd)
+	aliasCmd.AddCommand(aliasAddCmd)
+	aliasCmd.AddCommand(aliasRmCmd)
+}
@@ -0,0 +1,112 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/dotfile"
+)
+
+// allowCmd represents the allow command
+var allowCmd = &cobra.Command{
+	Use:   "allow [path]",
+	Short: "Trusts a .kcn file",
+	Long: `Trusts the contents of a .kcn file, found from path (the working directory
+by default) and its ancestors. Once trusted, entering its directory tree
+selects the file's context and namespace, and leaving the tree restores the
+previous selection. A file must be allowed again whenever it changes.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := loadDotfile(args)
+		if err == nil {
+			var trust *dotfile.Trust
+			trust, err = dotfile.NewTrust()
+			if err == nil {
+				err = trust.Allow(f)
+			}
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("allowed %s\n", f.Path)
+	},
+}
+
+// denyCmd represents the deny command
+var denyCmd = &cobra.Command{
+	Use:   "deny [path]",
+	Short: "Stops trusting a .kcn file",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		f, err := loadDotfile(args)
+		if err == nil {
+			var trust *dotfile.Trust
+			trust, err = dotfile.NewTrust()
+			if err == nil {
+				err = trust.Deny(f.Path)
+			}
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+
+		fmt.Printf("denied %s\n", f.Path)
+	},
+}
+
+// loadDotfile loads the .kcn file given in args, either as the file itself
+// or a directory to search from.
+func loadDotfile(args []string) (*dotfile.File, error) {
+	path := "."
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	if info, err := os.Stat(path); err != nil {
+		return nil, err
+	} else if info.IsDir() {
+		found, err := dotfile.Find(path, configPath())
+		if err != nil {
+			return nil, err
+		}
+		if len(found) == 0 {
+			return nil, errors.New("no .kcn file found")
+		}
+		path = found
+	}
+
+	return dotfile.Load(path)
+}
+
+func init() {
+	RootCmd.AddCommand(allowCmd)
+	RootCmd.AddCommand(denyCmd)
+}
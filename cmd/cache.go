@@ -0,0 +1,160 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/jesselang/kcn/internal/kubectl"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manages the cache of namespace lists",
+	Long: `Namespace lists are cached per context, so switching doesn't wait on the
+API server. A list older than cache.ttl in the config file (default 5m) is
+still used, while it's refreshed in the background. Set cache.ttl to 0 to
+disable the cache.`,
+}
+
+var cacheRefreshCmd = &cobra.Command{
+	Use:   "refresh [context...]",
+	Short: "Refreshes the namespace lists of contexts, all by default",
+	Run: func(cmd *cobra.Command, args []string) {
+		cached := mustCached()
+
+		contexts := args
+		if len(contexts) == 0 {
+			var err error
+			contexts, err = cached.GetContextList(cmd.Context())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s\n", err)
+				os.Exit(1)
+			}
+		}
+
+		failed := false
+		for _, v := range contexts {
+			if _, err := cached.RefreshNamespaceList(cmd.Context(), v); err != nil {
+				fmt.Fprintf(os.Stderr, "error: could not refresh %s: %s\n", v, err)
+				failed = true
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Removes all cached namespace lists",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := mustCached().Clear(); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+var cacheShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Lists the cached namespace lists",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		entries, err := mustCached().Entries()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "CONTEXT\tSERVER\tAGE\tNAMESPACES")
+		for _, v := range entries {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\n", v.Context, v.Server,
+				time.Since(v.Time).Round(time.Second), len(v.Namespaces))
+		}
+		w.Flush()
+	},
+}
+
+// newCached returns the namespace list cache over k.
+func newCached(k kubectl.Kubectl) (*kubectl.Cached, error) {
+	dir, err := kubectl.CacheDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return kubectl.NewCached(k, dir, viper.GetDuration("cache.ttl")), nil
+}
+
+func mustCached() *kubectl.Cached {
+	k, err := kubectl.New(viper.GetString("backend"),
+		viper.GetDuration("request_timeout"))
+	if err == nil {
+		var cached *kubectl.Cached
+		if cached, err = newCached(k); err == nil {
+			return cached
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "error: %s\n", err)
+	os.Exit(1)
+	return nil
+}
+
+// refreshInBackground starts kcn cache refresh for context, without waiting
+// for it.
+func refreshInBackground(context string) {
+	self, err := os.Executable()
+	if err != nil {
+		return
+	}
+
+	args := []string{"cache", "refresh", context,
+		"--backend", viper.GetString("backend"),
+		"--request-timeout", viper.GetDuration("request_timeout").String()}
+	if cfgFile != "" {
+		args = append(args, "--config", cfgFile)
+	}
+
+	c := exec.Command(self, args...)
+	if err := c.Start(); err == nil {
+		c.Process.Release()
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheRefreshCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	cacheCmd.AddCommand(cacheShowCmd)
+}
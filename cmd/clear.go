@@ -35,12 +35,12 @@ var clearCmd = &cobra.Command{
 	Short: "Clears kcn environment",
 	Long:  "Clears kcn environment",
 	Run: func(cmd *cobra.Command, args []string) {
-		st, err := state.ReadState(os.Getenv(envStatePath))
+		st, err := state.ReadState(os.Getenv(envStatePath), newKubectl())
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "kcn: %s\n", err)
 		} else {
-
-			if err := st.Clear(); err != nil {
+			st.Audit = auditLog()
+			if err := st.Clear(cmd.Context()); err != nil {
 				fmt.Fprintf(os.Stderr, "error: %s\n", err)
 				os.Exit(1)
 			}
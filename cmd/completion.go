@@ -0,0 +1,200 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/kubectl"
+	"github.com/jesselang/kcn/internal/match"
+	"github.com/jesselang/kcn/internal/recent"
+	"github.com/jesselang/kcn/internal/state"
+)
+
+// completionCmd represents the completion command
+var completionCmd = &cobra.Command{
+	Use:   "completion <bash|zsh|fish|powershell>",
+	Short: "Prints shell code for tab completion",
+	Long: `Prints shell code for tab completion of kcn's commands, contexts and
+namespaces, to be sourced from .*shrc:
+
+  source <(kcn completion bash)
+  source <(kcn completion zsh); compdef _kcn kcn
+  kcn completion fish | source`,
+	Args:      cobra.ExactValidArgs(1),
+	ValidArgs: []string{"bash", "zsh", "fish", "powershell"},
+	Run: func(cmd *cobra.Command, args []string) {
+		var err error
+		switch args[0] {
+		case "bash":
+			err = RootCmd.GenBashCompletion(os.Stdout)
+		case "zsh":
+			err = RootCmd.GenZshCompletion(os.Stdout)
+		case "fish":
+			err = RootCmd.GenFishCompletion(os.Stdout, true)
+		case "powershell":
+			err = RootCmd.GenPowerShellCompletion(os.Stdout)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// completeSwitch completes a context, then a namespace of that context, from
+// the namespace cache when it's enabled.
+func completeSwitch(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	k := newKubectl()
+	// completion works outside of an initialized session too
+	st, _ := state.ReadState(os.Getenv(envStatePath), k)
+
+	return switchCompletions(ctx, k, st, args, toComplete)
+}
+
+// switchCompletions completes args of a switch with k, ranking the session's
+// selections of st, when it isn't nil, and recent use first.
+func switchCompletions(ctx context.Context, k kubectl.Kubectl, st *state.State,
+	args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	var names, ranked []string
+	switch len(args) {
+	case 0:
+		contexts, err := k.GetContextList(ctx)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		for name := range cfg.Aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		names = append(names, contexts...)
+		if st != nil {
+			names = append(names, ".", "-")
+			for _, v := range st.Stack.Elements() {
+				ranked = append(ranked, v.Context)
+			}
+		}
+		if r := recentUse(); r != nil {
+			ranked = append(ranked, r.ContextNames()...)
+		}
+	case 1:
+		context := completedContext(ctx, k, st, args[0])
+		if len(context) == 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		namespaces, err := k.GetNamespaceList(ctx, context)
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+
+		names = namespaces
+		if st != nil {
+			names = append(names, "-")
+			for _, v := range st.Stack.Elements() {
+				if v.Context == context {
+					ranked = append(ranked, v.Namespace)
+				}
+			}
+		}
+		if r := recentUse(); r != nil {
+			ranked = append(ranked, r.NamespaceNames(context)...)
+		}
+	}
+
+	var completions []string
+	for _, v := range rank(names, ranked) {
+		if strings.HasPrefix(v, toComplete) {
+			completions = append(completions, v)
+		}
+	}
+
+	return completions, cobra.ShellCompDirectiveNoFileComp
+}
+
+// completedContext resolves the context argument as kcn would, or returns
+// nothing when it doesn't name a single context.
+func completedContext(ctx context.Context, k kubectl.Kubectl, st *state.State, arg string) string {
+	if st != nil {
+		// the namespace given after - applies to the current context
+		if arg == "-" {
+			if e, err := st.Stack.Peek(); err == nil {
+				return e.Context
+			}
+			return ""
+		}
+
+		st.Config = cfg
+		if e, err := st.Resolve(ctx, arg); err == nil {
+			return e.Context
+		}
+		return ""
+	}
+
+	if alias, ok := cfg.Aliases[arg]; ok {
+		arg = alias.Context
+	}
+
+	contexts, err := k.GetContextList(ctx)
+	if err != nil {
+		return ""
+	}
+	if found := match.Select(arg, contexts, false); len(found) == 1 {
+		return found[0]
+	}
+
+	return ""
+}
+
+// recentUse returns the use recorded across sessions, or nil.
+func recentUse() *recent.Recent {
+	path, err := recent.DefaultPath()
+	if err != nil {
+		return nil
+	}
+
+	r, err := recent.NewStore(path).Load()
+	if err != nil {
+		return nil
+	}
+
+	return r
+}
+
+func init() {
+	RootCmd.AddCommand(completionCmd)
+
+	RootCmd.ValidArgsFunction = completeSwitch
+	pushCmd.ValidArgsFunction = completeSwitch
+}
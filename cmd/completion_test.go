@@ -0,0 +1,104 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/config"
+	"github.com/jesselang/kcn/internal/kubectl"
+	"github.com/jesselang/kcn/internal/recent"
+	"github.com/jesselang/kcn/internal/state"
+)
+
+func TestSwitchCompletions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kcn-completion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	// recent use is read from the user's cache directory
+	for _, v := range []string{"XDG_CACHE_HOME", "HOME", "LocalAppData"} {
+		v := v
+		old, ok := os.LookupEnv(v)
+		os.Setenv(v, dir)
+		t.Cleanup(func() {
+			if ok {
+				os.Setenv(v, old)
+			} else {
+				os.Unsetenv(v)
+			}
+		})
+	}
+	path, err := recent.DefaultPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := recent.NewStore(path).Record("delta-prod", "app-z"); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	cfg = config.Config{Aliases: map[string]config.Alias{"dev": {Context: "alpha-dev"}}}
+
+	k := kubectl.NewMock()
+	st := state.NewEmpty(k, cfg)
+	st.Stack.Push(state.Element{Context: "bravo-stage", Namespace: "app-d"})
+	st.Stack.Push(state.Element{Context: "alpha-dev", Namespace: "app-b"})
+
+	cases := []struct {
+		st         *state.State
+		args       []string
+		toComplete string
+		expected   []string
+	}{
+		// the session's selections, then recent use, rank first
+		{st, nil, "", []string{"alpha-dev", "bravo-stage", "delta-prod", "dev", ".", "-"}},
+		{st, nil, "d", []string{"delta-prod", "dev"}},
+		{nil, nil, "", []string{"delta-prod", "dev", "alpha-dev", "bravo-stage"}},
+		// - takes a namespace of the current context
+		{st, []string{"-"}, "", []string{"app-b", "app-a", "app-c", "default", "kube-system", "-"}},
+		{st, []string{"."}, "app-", []string{"app-b", "app-a", "app-c"}},
+		{st, []string{"delta-prod"}, "app-", []string{"app-z", "app-x", "app-y"}},
+		{nil, []string{"dev"}, "app-", []string{"app-a", "app-b", "app-c"}},
+		{nil, []string{"bravo"}, "app-", []string{"app-d", "app-e", "app-f"}},
+		{st, []string{"nonexistent"}, "", nil},
+	}
+
+	for _, c := range cases {
+		completions, directive := switchCompletions(context.Background(), k, c.st, c.args, c.toComplete)
+		if !reflect.DeepEqual(completions, c.expected) {
+			t.Errorf("completing %v %q (session %t) = %v, expected %v",
+				c.args, c.toComplete, c.st != nil, completions, c.expected)
+		}
+		if directive != cobra.ShellCompDirectiveNoFileComp {
+			t.Errorf("expected no file completion for %v, got %d", c.args, directive)
+		}
+	}
+}
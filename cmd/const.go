@@ -20,8 +20,18 @@
 
 package cmd
 
+import (
+	"github.com/jesselang/kcn/internal/kubeconfig"
+)
+
 const (
 	envContext   = "KCN_CONTEXT"
 	envNamespace = "KCN_NAMESPACE"
 	envStatePath = "KCN_STATE_PATH"
+	envProtected = "KCN_PROTECTED"
+	// followed by the label's key, like KCN_LABEL_ENV
+	envLabelPrefix = "KCN_LABEL_"
+
+	envKubeconfig         = kubeconfig.EnvKubeconfig
+	envOriginalKubeconfig = kubeconfig.EnvOriginal
 )
@@ -0,0 +1,325 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/kubectl"
+	"github.com/jesselang/kcn/internal/match"
+)
+
+var (
+	listOutput string
+	listSort   string
+	listFilter string
+	listProbe  bool
+	// how many contexts are probed at once
+	probeParallel int
+)
+
+// contextInfo is a row of kcn contexts
+type contextInfo struct {
+	Name      string `json:"name"`
+	Cluster   string `json:"cluster"`
+	Server    string `json:"server"`
+	User      string `json:"user"`
+	Namespace string `json:"namespace"`
+	// selected by the session
+	Session bool `json:"session"`
+	// kubeconfig's current-context
+	Kubeconfig bool `json:"kubeconfig"`
+	Protected  bool `json:"protected"`
+	// configured for the context
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// set with --probe
+	Reachable  *bool  `json:"reachable,omitempty"`
+	Namespaces *int   `json:"namespaces,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// contextsCmd represents the contexts command
+var contextsCmd = &cobra.Command{
+	Use:   "contexts",
+	Short: "Lists kubeconfig's contexts",
+	Long: `Lists kubeconfig's contexts. In the CURRENT column, * marks the session's
+context and k marks kubeconfig's current-context. With --probe, each cluster
+is asked for its namespaces, to show whether it's reachable. Labels configured
+for contexts are listed with -o wide, and filter the list with --selector.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		checkListFlags()
+		st := readStateOrEmpty()
+		k := st.Kubectl()
+
+		names, err := k.GetContextList(cmd.Context())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		names = sortNames(filterLabeled(filterNames(names)), listSort, recentContexts())
+
+		var session string
+		if curr, err := st.Stack.Peek(); err == nil {
+			session = curr.Context
+		}
+		kubeconfigCurrent, _ := k.GetCurrentContext(cmd.Context())
+
+		infos := make([]contextInfo, len(names))
+		for i, v := range names {
+			infos[i] = contextInfo{
+				Name:       v,
+				Session:    v == session,
+				Kubeconfig: v == kubeconfigCurrent,
+				Protected:  cfg.IsProtected(v),
+			}
+			if labels := cfg.ContextLabels(v); len(labels) > 0 {
+				infos[i].Labels = labels
+			}
+			if details, err := k.GetContext(cmd.Context(), v); err == nil {
+				infos[i].Cluster = details.Cluster
+				infos[i].Server = details.Server
+				infos[i].User = details.User
+				infos[i].Namespace = details.Namespace
+			}
+		}
+
+		if listProbe {
+			probeContexts(cmd.Context(), k, infos)
+		}
+
+		switch listOutput {
+		case "name":
+			for _, v := range infos {
+				fmt.Println(v.Name)
+			}
+		case "json":
+			b, _ := json.MarshalIndent(infos, "", "  ")
+			fmt.Println(string(b))
+		case "", "wide":
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			columns := []string{"CURRENT", "NAME", "CLUSTER", "NAMESPACE"}
+			if listOutput == "wide" {
+				columns = append(columns, "SERVER", "USER", "PROTECTED", "LABELS")
+			}
+			if listProbe {
+				columns = append(columns, "REACHABLE", "NAMESPACES")
+			}
+			fmt.Fprintln(w, strings.Join(columns, "\t"))
+
+			for _, v := range infos {
+				row := []string{currentMark(v.Session, v.Kubeconfig), v.Name, v.Cluster, v.Namespace}
+				if listOutput == "wide" {
+					row = append(row, v.Server, v.User, yesNo(v.Protected), formatLabels(v.Labels))
+				}
+				if listProbe {
+					count := "-"
+					if v.Namespaces != nil {
+						count = fmt.Sprint(*v.Namespaces)
+					}
+					row = append(row, yesNo(*v.Reachable), count)
+				}
+				fmt.Fprintln(w, strings.Join(row, "\t"))
+			}
+			w.Flush()
+		default:
+			fmt.Fprintf(os.Stderr, "error: unknown output %s\n", listOutput)
+			os.Exit(1)
+		}
+	},
+}
+
+// probeContexts looks up the namespaces of contexts, probeParallel at a time,
+// bypassing the cache.
+func probeContexts(ctx context.Context, k kubectl.Kubectl, infos []contextInfo) {
+	parallel := probeParallel
+	if parallel < 1 || parallel > len(infos) {
+		parallel = len(infos)
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				probe(ctx, k, &infos[i])
+			}
+		}()
+	}
+
+	for i := range infos {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// probe looks up the namespaces of info's context, recording whether its
+// cluster is reachable.
+func probe(ctx context.Context, k kubectl.Kubectl, info *contextInfo) {
+	var namespaces []string
+	var err error
+	if r, ok := k.(kubectl.Refresher); ok {
+		namespaces, err = r.RefreshNamespaceList(ctx, info.Name)
+	} else {
+		namespaces, err = k.GetNamespaceList(ctx, info.Name)
+	}
+
+	reachable := err == nil
+	info.Reachable = &reachable
+	if err != nil {
+		info.Error = err.Error()
+		return
+	}
+	count := len(namespaces)
+	info.Namespaces = &count
+}
+
+// filterNames returns the names matching --filter.
+func filterNames(names []string) []string {
+	if len(listFilter) == 0 {
+		return names
+	}
+
+	var filtered []string
+	for _, v := range names {
+		if match.Pattern(listFilter, v) {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered
+}
+
+// filterLabeled returns the contexts matching --selector.
+func filterLabeled(contexts []string) []string {
+	if len(selectorFlag) == 0 {
+		return contexts
+	}
+
+	sel := flagSelector()
+	var filtered []string
+	for _, v := range contexts {
+		if sel.Matches(cfg.ContextLabels(v)) {
+			filtered = append(filtered, v)
+		}
+	}
+
+	return filtered
+}
+
+// formatLabels returns labels as key=value pairs, sorted by key.
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+// sortNames orders names by name, or by recent use and then name when by is
+// recent.
+func sortNames(names []string, by string, recent []string) []string {
+	sorted := append([]string{}, names...)
+	sort.Strings(sorted)
+
+	if by == "recent" {
+		return rank(sorted, recent)
+	}
+	return sorted
+}
+
+// checkListFlags exits when --sort is neither name nor recent.
+func checkListFlags() {
+	if listSort != "name" && listSort != "recent" {
+		fmt.Fprintf(os.Stderr, "error: unknown sort %s\n", listSort)
+		os.Exit(1)
+	}
+}
+
+func recentContexts() []string {
+	if r := recentUse(); r != nil {
+		return r.ContextNames()
+	}
+	return nil
+}
+
+func currentMark(session, kubeconfig bool) string {
+	mark := ""
+	if session {
+		mark += "*"
+	}
+	if kubeconfig {
+		mark += "k"
+	}
+	return mark
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// addListFlags adds the flags shared by the listing commands.
+func addListFlags(cmd *cobra.Command, outputs ...string) {
+	cmd.Flags().StringVarP(&listOutput, "output", "o", "",
+		"output format: "+strings.Join(outputs, ", ")+" (a table by default)")
+	cmd.Flags().StringVar(&listSort, "sort", "name",
+		"sort by name, or by recent use")
+	cmd.Flags().StringVarP(&listFilter, "filter", "f", "",
+		"only list names matching a glob or /regexp/")
+
+	cmd.RegisterFlagCompletionFunc("output",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return outputs, cobra.ShellCompDirectiveNoFileComp
+		})
+	cmd.RegisterFlagCompletionFunc("sort",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"name", "recent"}, cobra.ShellCompDirectiveNoFileComp
+		})
+}
+
+func init() {
+	RootCmd.AddCommand(contextsCmd)
+
+	addListFlags(contextsCmd, "wide", "json", "name")
+	contextsCmd.Flags().BoolVar(&listProbe, "probe", false,
+		"ask each cluster for its namespaces")
+	contextsCmd.Flags().IntVarP(&probeParallel, "parallel", "p", 4,
+		"how many clusters to probe at once, 0 for all")
+	addSelectorFlag(contextsCmd, "only list contexts whose configured labels match, like env=stage")
+}
@@ -0,0 +1,206 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jesselang/kcn/internal/config"
+	"github.com/jesselang/kcn/internal/kubectl"
+)
+
+func TestSortNames(t *testing.T) {
+	names := []string{"delta-prod", "alpha-dev", "bravo-stage"}
+
+	cases := []struct {
+		by       string
+		recent   []string
+		expected []string
+	}{
+		{"name", []string{"delta-prod"}, []string{"alpha-dev", "bravo-stage", "delta-prod"}},
+		{"recent", nil, []string{"alpha-dev", "bravo-stage", "delta-prod"}},
+		{"recent", []string{"delta-prod", "echo-prod", "bravo-stage"},
+			[]string{"delta-prod", "bravo-stage", "alpha-dev"}},
+	}
+
+	for _, c := range cases {
+		sorted := sortNames(names, c.by, c.recent)
+		if !reflect.DeepEqual(sorted, c.expected) {
+			t.Errorf("sortNames(%s, %v) = %v, expected %v", c.by, c.recent, sorted, c.expected)
+		}
+	}
+
+	if names[0] != "delta-prod" {
+		t.Errorf("expected names to be left alone, got %v", names)
+	}
+}
+
+func TestFilterNames(t *testing.T) {
+	saved := listFilter
+	t.Cleanup(func() { listFilter = saved })
+
+	names := []string{"alpha-dev", "bravo-stage", "delta-prod", "arn:aws:eks:us-east-1:123:cluster/prod"}
+
+	cases := []struct {
+		filter   string
+		expected []string
+	}{
+		{"", names},
+		{"*-dev", []string{"alpha-dev"}},
+		{"*prod", []string{"delta-prod", "arn:aws:eks:us-east-1:123:cluster/prod"}},
+		{"/^(alpha|bravo)-/", []string{"alpha-dev", "bravo-stage"}},
+		{"echo-*", nil},
+	}
+
+	for _, c := range cases {
+		listFilter = c.filter
+		if filtered := filterNames(names); !reflect.DeepEqual(filtered, c.expected) {
+			t.Errorf("filterNames with %q = %v, expected %v", c.filter, filtered, c.expected)
+		}
+	}
+}
+
+func TestFilterLabeled(t *testing.T) {
+	savedSelector, savedCfg := selectorFlag, cfg
+	t.Cleanup(func() { selectorFlag, cfg = savedSelector, savedCfg })
+
+	cfg = config.Config{
+		Contexts: []config.Context{
+			{Name: "*-prod", Labels: map[string]string{"env": "prod"}},
+			{Name: "/^(alpha|bravo)-/", Labels: map[string]string{"env": "nonprod", "team": "core"}},
+		},
+	}
+	contexts := []string{"alpha-dev", "bravo-stage", "delta-prod"}
+
+	cases := []struct {
+		selector string
+		expected []string
+	}{
+		{"", contexts},
+		{"env=prod", []string{"delta-prod"}},
+		{"env!=prod", []string{"alpha-dev", "bravo-stage"}},
+		{"team", []string{"alpha-dev", "bravo-stage"}},
+		{"!team", []string{"delta-prod"}},
+		{"env=stage", nil},
+	}
+
+	for _, c := range cases {
+		selectorFlag = c.selector
+		if filtered := filterLabeled(contexts); !reflect.DeepEqual(filtered, c.expected) {
+			t.Errorf("filterLabeled with %q = %v, expected %v", c.selector, filtered, c.expected)
+		}
+	}
+}
+
+func TestCurrentMark(t *testing.T) {
+	cases := []struct {
+		session, kubeconfig bool
+		expected            string
+	}{
+		{false, false, ""},
+		{true, false, "*"},
+		{false, true, "k"},
+		{true, true, "*k"},
+	}
+
+	for _, c := range cases {
+		if mark := currentMark(c.session, c.kubeconfig); mark != c.expected {
+			t.Errorf("currentMark(%t, %t) = %q, expected %q",
+				c.session, c.kubeconfig, mark, c.expected)
+		}
+	}
+}
+
+// probed wraps the kubectl mock, recording how many namespace lists are
+// looked up at once, and failing for unreachable contexts.
+type probed struct {
+	kubectl.Kubectl
+	unreachable string
+
+	mu      sync.Mutex
+	running int
+	most    int
+}
+
+func (k *probed) GetNamespaceList(ctx context.Context, context string) ([]string, error) {
+	k.mu.Lock()
+	k.running++
+	if k.running > k.most {
+		k.most = k.running
+	}
+	k.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	k.mu.Lock()
+	k.running--
+	k.mu.Unlock()
+
+	if context == k.unreachable {
+		return nil, errors.New("connection refused")
+	}
+	return k.Kubectl.GetNamespaceList(ctx, context)
+}
+
+func TestProbeContexts(t *testing.T) {
+	saved := probeParallel
+	t.Cleanup(func() { probeParallel = saved })
+
+	names := []string{"alpha-dev", "bravo-stage", "delta-prod"}
+
+	for _, parallel := range []int{1, 0} {
+		probeParallel = parallel
+		k := &probed{Kubectl: kubectl.NewMock(), unreachable: "bravo-stage"}
+
+		infos := make([]contextInfo, len(names))
+		for i, v := range names {
+			infos[i].Name = v
+		}
+		probeContexts(context.Background(), k, infos)
+
+		expected := parallel
+		if parallel < 1 {
+			expected = len(names)
+		}
+		if k.most != expected {
+			t.Errorf("--parallel %d probed %d at once, expected %d", parallel, k.most, expected)
+		}
+
+		for _, v := range infos {
+			reachable := v.Name != "bravo-stage"
+			if v.Reachable == nil || *v.Reachable != reachable {
+				t.Errorf("--parallel %d: expected %s reachable %t, got %+v", parallel, v.Name, reachable, v)
+				continue
+			}
+			if reachable && (v.Namespaces == nil || *v.Namespaces != 5 || len(v.Error) > 0) {
+				t.Errorf("--parallel %d: expected 5 namespaces for %s, got %+v", parallel, v.Name, v)
+			}
+			if !reachable && (v.Namespaces != nil || v.Error != "connection refused") {
+				t.Errorf("--parallel %d: expected an error for %s, got %+v", parallel, v.Name, v)
+			}
+		}
+	}
+}
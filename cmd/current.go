@@ -0,0 +1,139 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+var currentOutput string
+
+// selection is the output of kcn current, which scripts depend on
+type selection struct {
+	Context   string `json:"context" yaml:"context"`
+	Namespace string `json:"namespace" yaml:"namespace"`
+	// argument, alias, session, recent, kubeconfig, config or default
+	NamespaceFrom string `json:"namespace_from" yaml:"namespace_from"`
+	Alias         string `json:"alias" yaml:"alias"`
+	Cluster       string `json:"cluster" yaml:"cluster"`
+	Server        string `json:"server" yaml:"server"`
+	User          string `json:"user" yaml:"user"`
+	Protected     bool   `json:"protected" yaml:"protected"`
+	// configured for the context
+	Labels map[string]string `json:"labels" yaml:"labels"`
+	// unix time it was selected, 0 when unknown
+	Time int64 `json:"time" yaml:"time"`
+}
+
+// currentCmd represents the current command
+var currentCmd = &cobra.Command{
+	Use:     "current",
+	Aliases: []string{"status"},
+	Short:   "Prints the session's selection",
+	Long: `Prints the session's selection, with its cluster, server and user from
+kubeconfig, and where its namespace came from. Exits with 1 when nothing is
+selected.
+
+Output is one of:
+  plain  the selection's details, for people (default)
+  name   context/namespace
+  json   an object with the keys context, namespace, namespace_from, alias,
+         cluster, server, user, protected, labels and time
+  yaml   the same as json`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		st := readState()
+
+		curr, err := st.Stack.Peek()
+		if err != nil || len(curr.Context) == 0 {
+			fmt.Fprintln(os.Stderr, "kcn: nothing selected")
+			os.Exit(1)
+		}
+
+		sel := selection{
+			Context:       curr.Context,
+			Namespace:     curr.Namespace,
+			NamespaceFrom: curr.NamespaceFrom,
+			Alias:         curr.Alias,
+			Protected:     cfg.IsProtected(curr.Context),
+			Labels:        cfg.ContextLabels(curr.Context),
+			Time:          curr.Time,
+		}
+		if details, err := st.Kubectl().GetContext(cmd.Context(), curr.Context); err == nil {
+			sel.Cluster = details.Cluster
+			sel.Server = details.Server
+			sel.User = details.User
+		}
+
+		switch currentOutput {
+		case "name":
+			fmt.Printf("%s/%s\n", sel.Context, sel.Namespace)
+		case "json":
+			b, _ := json.MarshalIndent(sel, "", "  ")
+			fmt.Println(string(b))
+		case "yaml":
+			b, _ := yaml.Marshal(sel)
+			fmt.Print(string(b))
+		case "plain":
+			namespace := sel.Namespace
+			if len(sel.NamespaceFrom) > 0 {
+				namespace += " (from " + sel.NamespaceFrom + ")"
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintf(w, "context:\t%s\n", sel.Context)
+			fmt.Fprintf(w, "namespace:\t%s\n", namespace)
+			if len(sel.Alias) > 0 {
+				fmt.Fprintf(w, "alias:\t%s\n", sel.Alias)
+			}
+			fmt.Fprintf(w, "cluster:\t%s\n", sel.Cluster)
+			fmt.Fprintf(w, "server:\t%s\n", sel.Server)
+			fmt.Fprintf(w, "user:\t%s\n", sel.User)
+			if sel.Protected {
+				fmt.Fprintf(w, "protected:\tyes\n")
+			}
+			if len(sel.Labels) > 0 {
+				fmt.Fprintf(w, "labels:\t%s\n", formatLabels(sel.Labels))
+			}
+			w.Flush()
+		default:
+			fmt.Fprintf(os.Stderr, "error: unknown output %s\n", currentOutput)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(currentCmd)
+
+	currentCmd.Flags().StringVarP(&currentOutput, "output", "o", "plain",
+		"output format: plain, name, json or yaml")
+	currentCmd.RegisterFlagCompletionFunc("output",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"plain", "name", "json", "yaml"}, cobra.ShellCompDirectiveNoFileComp
+		})
+}
@@ -0,0 +1,205 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/match"
+	"github.com/jesselang/kcn/internal/run"
+	"github.com/jesselang/kcn/internal/state"
+)
+
+var eachOptions run.Options
+
+// eachCmd represents the each command
+var eachCmd = &cobra.Command{
+	Use:   "each (<pattern>[,<pattern>...] | -l <selector>) [namespace] -- <command> [args...]",
+	Short: "Runs a command in every matching context, in parallel",
+	Long: `Runs a command in every context matching a pattern, leaving the session's
+selection alone; it works outside of a session too. Patterns are globs, or
+regular expressions wrapped in slashes like /-prod$/. With --selector,
+contexts are chosen by their configured labels instead, like
+env=prod,region=eu. Output lines are prefixed by the context's name, or
+grouped by context with --group, and a summary of exit codes follows.
+
+A failure in one context doesn't stop the others. With --fail-fast, no more
+contexts start once one fails, though those already running finish. The exit
+code is the first non-zero one, in context order.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		dash := cmd.ArgsLenAtDash()
+		if len(selectorFlag) > 0 {
+			if dash < 0 || dash > 1 {
+				return errors.New("expected [namespace] before --")
+			}
+		} else if dash < 1 || dash > 2 {
+			return errors.New("expected <pattern> [namespace] before --")
+		}
+		if len(args) == dash {
+			return errors.New("no command given after --")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		st := readStateOrEmpty()
+
+		dash := cmd.ArgsLenAtDash()
+		selection, argv := args[:dash], args[dash:]
+
+		ctxList, err := st.Kubectl().GetContextList(cmd.Context())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+
+		var what string
+		var matches func(string) bool
+		if len(selectorFlag) > 0 {
+			sel := flagSelector()
+			what = "labels " + sel.String()
+			matches = func(context string) bool {
+				return sel.Matches(cfg.ContextLabels(context))
+			}
+			// only a namespace was given
+			selection = append([]string{""}, selection...)
+		} else {
+			what = selection[0]
+			matches = func(context string) bool {
+				return match.AnyPattern(strings.Split(selection[0], ","), context)
+			}
+		}
+
+		// contexts that can't be resolved are reported with the others
+		var contexts []string
+		var elements []*state.Element
+		failed := make(map[string]error)
+		for _, context := range ctxList {
+			if !matches(context) {
+				continue
+			}
+			contexts = append(contexts, context)
+
+			e, err := st.Resolve(cmd.Context(), append([]string{context}, selection[1:]...)...)
+			if err != nil {
+				failed[context] = err
+				continue
+			}
+			elements = append(elements, e)
+		}
+
+		if len(contexts) == 0 {
+			fmt.Fprintf(os.Stderr, "error: no contexts match %s\n", what)
+			os.Exit(1)
+		}
+
+		if !confirmCommand(elements, argv) {
+			fmt.Fprintln(os.Stderr, "error: context is protected, not running")
+			os.Exit(1)
+		}
+
+		// stops the remaining contexts, and those running, instead of exiting
+		setInterrupt(interruptCancel)
+		os.Exit(eachTargets(cmd.Context(), st, contexts, elements, failed, argv))
+	},
+}
+
+// eachTargets runs argv in every one of elements and prints a summary of
+// contexts in order, returning the first non-zero exit code. Contexts in
+// failed, or whose kubeconfig can't be written, are reported as failures
+// without running. Temporary kubeconfigs are removed before it returns.
+func eachTargets(ctx context.Context, st *state.State, contexts []string,
+	elements []*state.Element, failed map[string]error, argv []string) int {
+	var targets []run.Target
+	for _, e := range elements {
+		target, cleanup, err := newTarget(ctx, st, e)
+		if err != nil {
+			failed[e.Context] = err
+			continue
+		}
+		defer cleanup()
+
+		targets = append(targets, target)
+	}
+
+	results := make(map[string]run.Result)
+	for _, v := range run.Each(ctx, targets, argv, eachOptions, os.Stdout, os.Stderr) {
+		results[v.Target.Name] = v
+	}
+
+	exit := 0
+	w := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nCONTEXT\tEXIT\tTIME\tERROR")
+	for _, context := range contexts {
+		if err, ok := failed[context]; ok {
+			fmt.Fprintf(w, "%s\t-\t-\t%s\n", context, err)
+			if exit == 0 {
+				exit = 1
+			}
+			continue
+		}
+
+		v := results[context]
+		code, took, msg := "-", "-", "skipped"
+		if !v.Skipped {
+			code = fmt.Sprint(v.Code)
+			took = v.Duration.Round(time.Millisecond).String()
+			msg = ""
+			if v.Err != nil {
+				msg = v.Err.Error()
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Target.Name, code, took, msg)
+
+		if v.Failed() && exit == 0 {
+			exit = v.Code
+			if exit == 0 {
+				exit = 1
+			}
+		}
+	}
+	w.Flush()
+
+	return exit
+}
+
+func init() {
+	RootCmd.AddCommand(eachCmd)
+
+	eachCmd.Flags().IntVarP(&eachOptions.Parallel, "parallel", "p", 4,
+		"how many contexts to run in at once, 0 for all")
+	eachCmd.Flags().DurationVar(&eachOptions.Timeout, "timeout", 0,
+		"stop the command in a context after this long, like 30s")
+	eachCmd.Flags().BoolVar(&eachOptions.FailFast, "fail-fast", false,
+		"start no more contexts once one fails, letting those running finish")
+	eachCmd.Flags().BoolVarP(&eachOptions.Group, "group", "g", false,
+		"print each context's output together when it's done")
+	eachCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false,
+		"run in protected contexts without confirmation")
+	addSelectorFlag(eachCmd, "run in contexts whose configured labels match, like env=prod")
+}
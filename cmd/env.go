@@ -23,23 +23,34 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/jesselang/kcn/internal/kubeconfig"
+	"github.com/jesselang/kcn/internal/shell"
 	"github.com/jesselang/kcn/internal/state"
 )
 
 var (
-	envInit bool
+	envInit  bool
+	envShell string
 )
 
 // envCmd represents the env command
 var envCmd = &cobra.Command{
 	Use:   "env",
-	Short: "",
-	Long:  "",
+	Short: "Prints shell code for the session's selection",
+	Long: `Prints shell code for the session's selection. With --init, prints the
+shell functions that keep the session in sync, to be sourced from .*shrc.`,
 	Run: func(cmd *cobra.Command, args []string) {
-		st, err := state.ReadState(os.Getenv(envStatePath))
+		sh, err := shell.Resolve(envShell)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+
+		st, err := state.ReadState(os.Getenv(envStatePath), newKubectl())
 		if !envInit {
 			// This branch is likely being executed using shell's process
 			// substitution. Non-zero exit codes won't propagate through
@@ -61,38 +72,70 @@ var envCmd = &cobra.Command{
 				}
 			}
 
-			fmt.Printf("%s=%s\n", envContext, curr.Context)
-			fmt.Printf("%s=%s\n", envNamespace, curr.Namespace)
+			var protected string
+			if cfg.IsProtected(curr.Context) {
+				protected = "1"
+			}
+
+			fmt.Print(sh.Env(append([]shell.Var{
+				{Name: envContext, Value: curr.Context},
+				{Name: envNamespace, Value: curr.Namespace},
+				{Name: envProtected, Value: protected},
+			}, labelVars(curr.Context)...)))
 		} else {
-			// XXX: won't work on non-bash shells or windows
+			// XXX: won't work on windows
+			var vars []shell.Var
 			if err != nil {
-				st, err = state.NewState(nil)
+				st, err = state.NewState(cmd.Context(), newKubectl(), cfg)
 
 				if err != nil {
 					fmt.Fprintf(os.Stderr, "error: %s\n", err)
 					os.Exit(1)
 				}
 
-				fmt.Printf("export %s= %s=\n", envContext, envNamespace)
+				vars = append(vars,
+					shell.Var{Name: envContext},
+					shell.Var{Name: envNamespace},
+					shell.Var{Name: envProtected})
+				vars = append(vars, labelVars("")...)
 			}
 
-			fmt.Printf("export %s=%s\n", envStatePath, st.Path())
-			fmt.Println(
-				`
-kcn() {
-	command kcn "$@"
-	kcn_code=$?
-	source <(command kcn env)
-	[[ $kcn_code -eq 0 ]] || return $kcn_code
-};`)
-			// https://github.com/kubernetes/kubernetes/issues/27308#issuecomment-309207951
-			fmt.Println(`alias kubectl="kubectl \
-\${KCN_CONTEXT/[[:alnum:]-]*/--context=\${KCN_CONTEXT}} \
-\${KCN_NAMESPACE/[[:alnum:]-]*/--namespace=\${KCN_NAMESPACE}}"`)
+			vars = append(vars,
+				shell.Var{Name: envStatePath, Value: st.Path()},
+				shell.Var{Name: envOriginalKubeconfig, Value: kubeconfig.Original()},
+				shell.Var{Name: envKubeconfig, Value: kubeconfig.Layer(st.KubeconfigPath())})
+
+			fmt.Print(shell.Init(sh, vars))
 		}
 	},
 }
 
+// labelVars returns a variable for every configured label key, set to the
+// value of context's label, so that labels of other contexts are unset.
+func labelVars(context string) []shell.Var {
+	labels := cfg.ContextLabels(context)
+
+	var vars []shell.Var
+	for _, key := range cfg.LabelKeys() {
+		vars = append(vars, shell.Var{Name: labelVar(key), Value: labels[key]})
+	}
+
+	return vars
+}
+
+// labelVar returns the variable name of a label key, like KCN_LABEL_TEAM_NAME
+// for team-name.
+func labelVar(key string) string {
+	name := []rune(strings.ToUpper(key))
+	for i, r := range name {
+		if !(r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			name[i] = '_'
+		}
+	}
+
+	return envLabelPrefix + string(name)
+}
+
 func init() {
 	RootCmd.AddCommand(envCmd)
 
@@ -106,5 +149,11 @@ func init() {
 	// is called directly, e.g.:
 	// envCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	envCmd.Flags().BoolVarP(&envInit, "init", "i", false, "Initialize state (source from .*shrc)")
+	envCmd.Flags().StringVarP(&envShell, "shell", "s", "",
+		"shell to print code for: "+strings.Join(shell.Names(), ", ")+" (detected by default)")
+	envCmd.RegisterFlagCompletionFunc("shell",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return shell.Names(), cobra.ShellCompDirectiveNoFileComp
+		})
 
 }
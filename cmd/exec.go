@@ -0,0 +1,187 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/kubeconfig"
+	"github.com/jesselang/kcn/internal/run"
+	"github.com/jesselang/kcn/internal/state"
+)
+
+// execCmd represents the exec command
+var execCmd = &cobra.Command{
+	Use:   "exec <context>[,<context>...] [namespace] -- <command> [args...]",
+	Short: "Runs a command in another context without switching to it",
+	Long: `Runs a command with KUBECONFIG selecting the given context and namespace,
+leaving the session's selection alone, and works outside of a session too, as
+in scripts. Contexts and namespaces are matched the same as kcn <context>
+[namespace]. Given several comma separated contexts, the command runs in each
+in turn, with its output prefixed by the context's name.
+
+The exit code is the command's, or the first non-zero one of several.`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		dash := cmd.ArgsLenAtDash()
+		if dash < 1 || dash > 2 {
+			return errors.New("expected <context> [namespace] before --")
+		}
+		if len(args) == dash {
+			return errors.New("no command given after --")
+		}
+		return nil
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		st := readStateOrEmpty()
+
+		dash := cmd.ArgsLenAtDash()
+		selection, argv := args[:dash], args[dash:]
+
+		var elements []*state.Element
+		for _, context := range strings.Split(selection[0], ",") {
+			e, err := st.Resolve(cmd.Context(), append([]string{context}, selection[1:]...)...)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s\n", err)
+				os.Exit(1)
+			}
+			elements = append(elements, e)
+		}
+
+		if !confirmCommand(elements, argv) {
+			fmt.Fprintln(os.Stderr, "error: context is protected, not running")
+			os.Exit(1)
+		}
+
+		// a child in the foreground, like a shell or k9s, handles Ctrl-C
+		setInterrupt(interruptIgnore)
+		os.Exit(execEach(cmd.Context(), st, elements, argv))
+	},
+}
+
+// execEach runs argv in each of elements in turn, returning the first non-zero
+// exit code. Temporary kubeconfigs are removed before it returns.
+func execEach(ctx context.Context, st *state.State, elements []*state.Element, argv []string) int {
+	var mu sync.Mutex
+	exit := 0
+	for _, e := range elements {
+		target, cleanup, err := newTarget(ctx, st, e)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			return 1
+		}
+		defer cleanup()
+
+		var stdout, stderr io.WriteCloser = os.Stdout, os.Stderr
+		if len(elements) > 1 {
+			stdout = run.NewPrefixWriter(os.Stdout, target.Name+": ", &mu)
+			stderr = run.NewPrefixWriter(os.Stderr, target.Name+": ", &mu)
+		}
+
+		code, err := run.Command(ctx, target, argv, stdout, stderr)
+		if len(elements) > 1 {
+			stdout.Close()
+			stderr.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		}
+		if code != 0 && exit == 0 {
+			exit = code
+		}
+	}
+
+	return exit
+}
+
+// newTarget writes a kubeconfig selecting e, returning the environment that
+// uses it and a function to remove it.
+func newTarget(ctx context.Context, st *state.State, e *state.Element) (run.Target, func(), error) {
+	dir, err := ioutil.TempDir("", "kcn-exec-")
+	if err != nil {
+		return run.Target{}, nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	path := filepath.Join(dir, "kubeconfig")
+	if err := st.WriteKubeconfig(ctx, path, e); err != nil {
+		cleanup()
+		return run.Target{}, nil, err
+	}
+
+	var protected string
+	if cfg.IsProtected(e.Context) {
+		protected = "1"
+	}
+
+	env := []string{
+		envKubeconfig + "=" + kubeconfig.Layer(path),
+		envOriginalKubeconfig + "=" + kubeconfig.Original(),
+		envContext + "=" + e.Context,
+		envNamespace + "=" + e.Namespace,
+		envProtected + "=" + protected,
+	}
+	for _, v := range labelVars(e.Context) {
+		env = append(env, v.Name+"="+v.Value)
+	}
+
+	return run.Target{Name: e.Context, Env: env}, cleanup, nil
+}
+
+// confirmCommand asks once before running argv in any protected contexts of
+// elements, unless it's a kubectl command whose verb isn't protected.
+func confirmCommand(elements []*state.Element, argv []string) bool {
+	var protected []string
+	for _, e := range elements {
+		if cfg.IsProtected(e.Context) {
+			protected = append(protected, e.Context)
+		}
+	}
+
+	if yesFlag || len(protected) == 0 {
+		return true
+	}
+
+	if filepath.Base(argv[0]) == "kubectl" && !cfg.IsProtectedVerb(kubectlVerb(argv[1:])) {
+		return true
+	}
+
+	return confirm(fmt.Sprintf("run `%s` in protected context %s?",
+		strings.Join(argv, " "), strings.Join(protected, ", ")))
+}
+
+func init() {
+	RootCmd.AddCommand(execCmd)
+
+	execCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false,
+		"run in protected contexts without confirmation")
+	execCmd.Flags().BoolVar(&exactFlag, "exact", false,
+		"only accept exact context and namespace names")
+}
@@ -0,0 +1,76 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/jesselang/kcn/internal/state"
+)
+
+var gcDryRun bool
+
+// gcCmd represents the gc command
+var gcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Removes state files of sessions that have ended",
+	Long: `Removes state files whose shell is no longer running, or that haven't
+been used for longer than --max-age (gc.max_age in the config file). The
+current session's state file is never removed.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dir, err := state.Dir()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+
+		orphans, err := state.Collect(dir, viper.GetDuration("gc.max_age"),
+			os.Getenv(envStatePath), gcDryRun)
+
+		verb := "removed"
+		if gcDryRun {
+			verb = "would remove"
+		}
+		for _, v := range orphans {
+			fmt.Printf("%s %s: %s\n", verb, v.Path, v.Reason)
+		}
+
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(gcCmd)
+
+	gcCmd.Flags().BoolVarP(&gcDryRun, "dry-run", "n", false,
+		"report what would be removed without removing it")
+	gcCmd.Flags().Duration("max-age", 0,
+		"remove state files unused for longer, 0 to only check the shell (default 720h)")
+	viper.BindPFlag("gc.max_age", gcCmd.Flags().Lookup("max-age"))
+}
@@ -0,0 +1,103 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// kubectl flags that take a separate value, which must be skipped to find
+// the verb
+var kubectlValueFlags = map[string]bool{
+	"-n": true, "--namespace": true,
+	"--context": true, "--cluster": true, "--user": true,
+	"-s": true, "--server": true, "--kubeconfig": true,
+	"--as": true, "--as-group": true, "--token": true,
+	"-v": true, "--v": true, "--request-timeout": true,
+}
+
+// guardCmd is run by the kubectl wrapper function when a protected context
+// is active
+var guardCmd = &cobra.Command{
+	Use:    "guard -- <kubectl args>",
+	Short:  "Confirms mutating kubectl commands in protected contexts",
+	Hidden: true,
+	Run: func(cmd *cobra.Command, args []string) {
+		context := os.Getenv(envContext)
+		if !cfg.IsProtected(context) || !cfg.IsProtectedVerb(kubectlVerb(args)) {
+			return
+		}
+
+		if !confirm(fmt.Sprintf("run `kubectl %s` in protected context %s?",
+			strings.Join(args, " "), context)) {
+			os.Exit(1)
+		}
+	},
+}
+
+// kubectlVerb returns the first argument that isn't a flag or a flag's value.
+func kubectlVerb(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if strings.HasPrefix(args[i], "-") {
+			if kubectlValueFlags[args[i]] {
+				i++
+			}
+			continue
+		}
+
+		return args[i]
+	}
+
+	return ""
+}
+
+// confirm asks a yes or no question on the terminal, answering no when there
+// is no terminal to ask on.
+func confirm(question string) bool {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return false
+	}
+	defer tty.Close()
+
+	fmt.Fprintf(tty, "kcn: %s [y/N] ", question)
+
+	answer, err := bufio.NewReader(tty).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(guardCmd)
+}
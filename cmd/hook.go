@@ -0,0 +1,95 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/dotfile"
+)
+
+// hookCmd is run by the shell hooks set up by kcn env --init
+var hookCmd = &cobra.Command{
+	Use:    "hook",
+	Short:  "Runs shell hooks",
+	Hidden: true,
+}
+
+var hookChpwdCmd = &cobra.Command{
+	Use:   "chpwd",
+	Short: "Applies the .kcn file of the working directory",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		st := readState()
+
+		f, err := findDotfile(".")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kcn: %s\n", err)
+			os.Exit(1)
+		}
+
+		if f == nil {
+			err = st.Chdir(cmd.Context(), "")
+		} else {
+			err = st.Chdir(cmd.Context(), f.Path, f.Args()...)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "kcn: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// findDotfile returns the trusted .kcn file governing dir, or nil when there
+// is none. Untrusted files are reported and ignored.
+func findDotfile(dir string) (*dotfile.File, error) {
+	path, err := dotfile.Find(dir, configPath())
+	if err != nil || len(path) == 0 {
+		return nil, err
+	}
+
+	f, err := dotfile.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	trust, err := dotfile.NewTrust()
+	if err != nil {
+		return nil, err
+	}
+
+	if !trust.Allowed(f) {
+		fmt.Fprintf(os.Stderr,
+			"kcn: %s is not allowed, run `kcn allow` to trust its contents\n",
+			f.Path)
+		return nil, nil
+	}
+
+	return f, nil
+}
+
+func init() {
+	RootCmd.AddCommand(hookCmd)
+	hookCmd.AddCommand(hookChpwdCmd)
+}
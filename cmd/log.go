@@ -0,0 +1,185 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/audit"
+)
+
+// times are printed and parsed like history's
+const timeLayout = "2006-01-02 15:04:05"
+
+var (
+	logQuery  struct{ since, until, context, session, user string }
+	logTail   int
+	logOutput string
+)
+
+// logCmd represents the log command
+var logCmd = &cobra.Command{
+	Use:   "log",
+	Short: "Prints the audit log of switches in all sessions",
+	Long: `Prints the audit log, which records every switch, swap, jump, pop and clear
+in all sessions, oldest first. --since and --until take a duration ago like
+24h, a date like 2020-01-31, or a time like "2020-01-31 15:04" or RFC 3339.
+--context matches the context switched from or to by name, glob or /regexp/,
+and --session . is this shell's session.
+
+Output is a table, or with -o json the log's own JSON lines, with the keys
+time, user, host, session, tty, action, args, previous and current.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		l := auditLog()
+		if l == nil {
+			fmt.Fprintln(os.Stderr, "error: the audit log is disabled")
+			os.Exit(1)
+		}
+
+		q := audit.Query{
+			Context: logQuery.context,
+			Session: logQuery.session,
+			User:    logQuery.user,
+		}
+		if q.Session == "." {
+			q.Session = filepath.Base(os.Getenv(envStatePath))
+		}
+
+		var err error
+		if q.Since, err = parseTime(logQuery.since); err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid --since: %s\n", err)
+			os.Exit(1)
+		}
+		if q.Until, err = parseTime(logQuery.until); err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid --until: %s\n", err)
+			os.Exit(1)
+		}
+
+		entries, err := l.Read(q)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		if logTail > 0 && len(entries) > logTail {
+			entries = entries[len(entries)-logTail:]
+		}
+
+		switch logOutput {
+		case "json":
+			for _, e := range entries {
+				b, _ := json.Marshal(e)
+				fmt.Println(string(b))
+			}
+		case "":
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			fmt.Fprintln(w, "TIME\tUSER\tHOST\tSESSION\tACTION\tFROM\tTO\tARGS")
+			for _, e := range entries {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+					e.Time.Local().Format(timeLayout), e.User, e.Host, e.Session,
+					e.Action, formatSelection(e.Previous), formatSelection(e.Current),
+					strings.Join(e.Args, " "))
+			}
+			w.Flush()
+		default:
+			fmt.Fprintf(os.Stderr, "error: unknown output %s\n", logOutput)
+			os.Exit(1)
+		}
+	},
+}
+
+// auditLog returns the configured audit log, or nil when it's disabled.
+func auditLog() *audit.Log {
+	if cfg.Audit.Disabled {
+		return nil
+	}
+
+	path := cfg.Audit.Path
+	if len(path) == 0 {
+		var err error
+		if path, err = audit.DefaultPath(); err != nil {
+			return nil
+		}
+	}
+
+	return audit.NewLog(path, cfg.Audit.MaxSize, cfg.Audit.MaxFiles)
+}
+
+// parseTime parses a duration ago, a date or a time, in local time unless
+// it's RFC 3339. An empty value is the zero time.
+func parseTime(value string) (time.Time, error) {
+	if len(value) == 0 {
+		return time.Time{}, nil
+	}
+
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{timeLayout, "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, value, time.Local); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("%s is not a duration, date or time", value)
+}
+
+func formatSelection(s *audit.Selection) string {
+	if s == nil {
+		return "-"
+	}
+
+	return s.Context + "/" + s.Namespace
+}
+
+func init() {
+	RootCmd.AddCommand(logCmd)
+
+	logCmd.Flags().StringVar(&logQuery.since, "since", "",
+		"only entries at or after this time, like 24h or 2020-01-31")
+	logCmd.Flags().StringVar(&logQuery.until, "until", "",
+		"only entries before this time")
+	logCmd.Flags().StringVarP(&logQuery.context, "context", "c", "",
+		"only switches from or to contexts matching a glob or /regexp/")
+	logCmd.Flags().StringVar(&logQuery.session, "session", "",
+		"only entries of a session, or . for this one")
+	logCmd.Flags().StringVar(&logQuery.user, "user", "",
+		"only entries of a user")
+	logCmd.Flags().IntVarP(&logTail, "tail", "n", 0,
+		"only the last n entries")
+	logCmd.Flags().StringVarP(&logOutput, "output", "o", "",
+		"output format: json (a table by default)")
+	logCmd.RegisterFlagCompletionFunc("output",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return []string{"json"}, cobra.ShellCompDirectiveNoFileComp
+		})
+}
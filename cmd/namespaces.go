@@ -0,0 +1,158 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/kubectl"
+)
+
+// namespaceInfo is a row of kcn namespaces
+type namespaceInfo struct {
+	Name string `json:"name"`
+	// selected by the session
+	Session bool `json:"session"`
+	// set on the context in kubeconfig
+	Kubeconfig bool `json:"kubeconfig"`
+	// how many times it was selected, across sessions
+	Uses int `json:"uses"`
+}
+
+// namespacesCmd represents the namespaces command
+var namespacesCmd = &cobra.Command{
+	Use:   "namespaces [context | -l <selector>]",
+	Short: "Lists the namespaces of a context, the session's by default",
+	Long: `Lists the namespaces of a context, the session's by default. In the CURRENT
+column, * marks the session's namespace and k marks the namespace set on the
+context in kubeconfig.`,
+	Args: cobra.MaximumNArgs(1),
+	ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) > 0 {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return completeSwitch(cmd, args, toComplete)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		checkListFlags()
+		st := readStateOrEmpty()
+
+		if len(args) > 0 && len(selectorFlag) > 0 {
+			fmt.Fprintln(os.Stderr, "error: a context can't be given with --selector")
+			os.Exit(1)
+		}
+
+		arg := "."
+		if len(args) > 0 {
+			arg = args[0]
+		} else if len(selectorFlag) > 0 {
+			context, err := st.ByLabels(cmd.Context(), flagSelector())
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s\n", err)
+				os.Exit(1)
+			}
+			arg = context
+		}
+
+		e, err := st.Resolve(cmd.Context(), arg)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+
+		names, err := st.Kubectl().GetNamespaceList(cmd.Context(), e.Context)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+
+		var recent []string
+		uses := map[string]int{}
+		if r := recentUse(); r != nil {
+			recent = r.NamespaceNames(e.Context)
+			if c, ok := r.Contexts[e.Context]; ok {
+				uses = c.Namespaces
+			}
+		}
+		names = sortNames(filterNames(names), listSort, recent)
+
+		var session string
+		if curr, err := st.Stack.Peek(); err == nil && curr.Context == e.Context {
+			session = curr.Namespace
+		}
+		var configured string
+		if details, err := st.Kubectl().GetContext(cmd.Context(), e.Context); err == nil {
+			configured = details.Namespace
+		}
+
+		infos := make([]namespaceInfo, len(names))
+		for i, v := range names {
+			infos[i] = namespaceInfo{
+				Name:       v,
+				Session:    v == session,
+				Kubeconfig: v == configured || (len(configured) == 0 && v == kubectl.DefaultNamespace),
+				Uses:       uses[v],
+			}
+		}
+
+		switch listOutput {
+		case "name":
+			for _, v := range infos {
+				fmt.Println(v.Name)
+			}
+		case "json":
+			b, _ := json.MarshalIndent(infos, "", "  ")
+			fmt.Println(string(b))
+		case "", "wide":
+			w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+			columns := []string{"CURRENT", "NAME"}
+			if listOutput == "wide" {
+				columns = append(columns, "USES")
+			}
+			fmt.Fprintln(w, strings.Join(columns, "\t"))
+
+			for _, v := range infos {
+				row := []string{currentMark(v.Session, v.Kubeconfig), v.Name}
+				if listOutput == "wide" {
+					row = append(row, fmt.Sprint(v.Uses))
+				}
+				fmt.Fprintln(w, strings.Join(row, "\t"))
+			}
+			w.Flush()
+		default:
+			fmt.Fprintf(os.Stderr, "error: unknown output %s\n", listOutput)
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(namespacesCmd)
+
+	addListFlags(namespacesCmd, "wide", "json", "name")
+	addSelectorFlag(namespacesCmd, "list the namespaces of the context labeled like env=stage")
+}
@@ -0,0 +1,146 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"context"
+	"os"
+
+	"github.com/jesselang/kcn/internal/picker"
+	"github.com/jesselang/kcn/internal/state"
+)
+
+// pickArgs completes args for State.Update by letting the user pick a context
+// (when none was given) and then a namespace.
+func pickArgs(ctx context.Context, st *state.State, args []string) ([]string, error) {
+	if len(args) == 0 {
+		contexts, err := st.Kubectl().GetContextList(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		context, err := pickContext(ctx, st, contexts)
+		if err != nil {
+			return nil, err
+		}
+		args = []string{context}
+	}
+
+	// resolved here, so the namespaces offered are those of the context
+	// that's selected
+	e, err := st.Resolve(ctx, args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	namespace, err := pickNamespace(ctx, st, e.Context)
+	if err != nil {
+		return nil, err
+	}
+	if len(namespace) == 0 {
+		return args, nil
+	}
+
+	return []string{e.Context, namespace}, nil
+}
+
+// pickContext lets the user pick one of contexts.
+func pickContext(ctx context.Context, st *state.State, contexts []string) (string, error) {
+	var current string
+	var recent []string
+	if curr, err := st.Stack.Peek(); err == nil {
+		current = curr.Context
+	}
+	for _, v := range st.Stack.Elements() {
+		recent = append(recent, v.Context)
+	}
+	if r := recentUse(); r != nil {
+		recent = append(recent, r.ContextNames()...)
+	}
+
+	return pick("context", rank(contexts, recent), current)
+}
+
+// pickNamespace returns an empty namespace when the namespace list can't be
+// retrieved, leaving the choice to State.Update.
+func pickNamespace(ctx context.Context, st *state.State, context string) (string, error) {
+	namespaces, err := st.Kubectl().GetNamespaceList(ctx, context)
+	if err != nil || len(namespaces) == 0 {
+		return "", nil
+	}
+
+	var current string
+	var recent []string
+	if curr, err := st.Stack.Peek(); err == nil && curr.Context == context {
+		current = curr.Namespace
+	}
+	for _, v := range st.Stack.Elements() {
+		if v.Context == context {
+			recent = append(recent, v.Namespace)
+		}
+	}
+	if r := recentUse(); r != nil {
+		recent = append(recent, r.NamespaceNames(context)...)
+	}
+
+	return pick(context+" namespace", rank(namespaces, recent), current)
+}
+
+func pick(prompt string, names []string, current string) (string, error) {
+	items := make([]picker.Item, 0, len(names))
+	for _, v := range names {
+		items = append(items, picker.Item{Name: v, Current: v == current})
+	}
+
+	choice, err := picker.Pick(prompt, items)
+	if err == picker.ErrNoTerminal {
+		picker.List(os.Stdout, items)
+	}
+
+	return choice, err
+}
+
+// rank orders names with recently used names first, in the order of recent,
+// followed by the rest in their original order.
+func rank(names, recent []string) []string {
+	known := map[string]bool{}
+	for _, v := range names {
+		known[v] = true
+	}
+
+	ranked := make([]string, 0, len(names))
+	seen := map[string]bool{}
+	for _, v := range recent {
+		if known[v] && !seen[v] {
+			ranked = append(ranked, v)
+			seen[v] = true
+		}
+	}
+
+	for _, v := range names {
+		if !seen[v] {
+			ranked = append(ranked, v)
+			seen[v] = true
+		}
+	}
+
+	return ranked
+}
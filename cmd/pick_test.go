@@ -0,0 +1,51 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestRank(t *testing.T) {
+	names := []string{"alpha-dev", "bravo-stage", "delta-prod", "."}
+
+	cases := []struct {
+		recent   []string
+		expected []string
+	}{
+		{nil, []string{"alpha-dev", "bravo-stage", "delta-prod", "."}},
+		{[]string{"delta-prod"}, []string{"delta-prod", "alpha-dev", "bravo-stage", "."}},
+		// duplicates and unknown names are dropped
+		{[]string{"bravo-stage", "echo-prod", "delta-prod", "bravo-stage"},
+			[]string{"bravo-stage", "delta-prod", "alpha-dev", "."}},
+	}
+
+	for _, c := range cases {
+		if ranked := rank(names, c.recent); !reflect.DeepEqual(ranked, c.expected) {
+			t.Errorf("rank(%v) = %v, expected %v", c.recent, ranked, c.expected)
+		}
+	}
+
+	if ranked := rank([]string{"app-a", "app-a"}, nil); !reflect.DeepEqual(ranked, []string{"app-a"}) {
+		t.Errorf("expected duplicate names once, got %v", ranked)
+	}
+}
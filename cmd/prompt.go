@@ -0,0 +1,129 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jesselang/kcn/internal/prompt"
+	"github.com/jesselang/kcn/internal/state"
+)
+
+var (
+	promptFormat  string
+	promptNoColor bool
+	promptShell   string
+)
+
+// promptCmd represents the prompt command
+var promptCmd = &cobra.Command{
+	Use:   "prompt [" + strings.Join(prompt.Integrations(), "|") + "]",
+	Short: "Prints the session's selection for a shell prompt",
+	Long: `Prints the session's selection for a shell prompt, rendering --format (or
+prompt.format in the config file), a go template with the fields:
+
+  .Context .Namespace .Cluster .Alias .Protected .Depth .Labels .Color .Reset
+
+where .Cluster is the cluster's short name, .Labels holds the context's
+configured labels, like {{.Labels.env}}, and .Color and .Reset color the
+context as configured in contexts. Only the state file is read, so it's
+cheap enough to run on every prompt. Nothing is printed without a selection.
+
+Given the name of a prompt framework, prints a snippet to configure it.`,
+	Args:      cobra.MaximumNArgs(1),
+	ValidArgs: prompt.Integrations(),
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 0 {
+			snippet, err := prompt.Integration(args[0])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: %s\n", err)
+				os.Exit(1)
+			}
+			fmt.Print(snippet)
+			return
+		}
+
+		path := os.Getenv(envStatePath)
+		if len(path) == 0 {
+			return
+		}
+
+		// without a kubectl implementation, which is never needed here
+		st, err := state.ReadState(path, nil)
+		if err != nil {
+			return
+		}
+
+		curr, err := st.Stack.Peek()
+		if err != nil || len(curr.Context) == 0 {
+			return
+		}
+
+		data := prompt.Data{
+			Context:   curr.Context,
+			Namespace: curr.Namespace,
+			Cluster:   prompt.ShortName(curr.Context),
+			Alias:     curr.Alias,
+			Protected: cfg.IsProtected(curr.Context),
+			Depth:     st.Stack.Length(),
+			Labels:    cfg.ContextLabels(curr.Context),
+		}
+
+		if !promptNoColor {
+			color, reset, err := prompt.Color(cfg.ContextColor(curr.Context))
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "kcn: %s\n", err)
+			}
+			data.Color = prompt.Escape(promptShell, color)
+			data.Reset = prompt.Escape(promptShell, reset)
+		}
+
+		format := promptFormat
+		if len(format) == 0 {
+			format = cfg.Prompt.Format
+		}
+		if len(format) == 0 {
+			format = prompt.DefaultFormat
+		}
+
+		out, err := prompt.Render(format, data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(out)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(promptCmd)
+
+	promptCmd.Flags().StringVarP(&promptFormat, "format", "f", "",
+		"go template to render (default "+prompt.DefaultFormat+")")
+	promptCmd.Flags().BoolVar(&promptNoColor, "no-color", false,
+		"leave out the context's color")
+	promptCmd.Flags().StringVar(&promptShell, "shell", "",
+		"escape colors for the prompt of this shell: bash or zsh")
+}
@@ -0,0 +1,90 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var dirsVerbose bool
+
+// pushCmd represents the push command
+var pushCmd = &cobra.Command{
+	Use:   "push [context] [namespace]",
+	Short: "Selects a context and namespace, to be undone with kcn pop",
+	Long: `Selects a context and namespace, the same as kcn [context] [namespace]. The
+selection is pushed onto the session's stack, and kcn pop returns to the one
+before it.`,
+	Args: cobra.RangeArgs(0, 2),
+	Run:  runSwitch,
+}
+
+// popCmd represents the pop command
+var popCmd = &cobra.Command{
+	Use:   "pop",
+	Short: "Discards the current selection, returning to the previous one",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := readState().Pop(cmd.Context()); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %s\n", err)
+			os.Exit(1)
+		}
+	},
+}
+
+// dirsCmd represents the dirs command
+var dirsCmd = &cobra.Command{
+	Use:   "dirs",
+	Short: "Prints the session's stack, current selection first",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		st := readState()
+
+		var entries []string
+		for i, v := range st.Stack.Elements() {
+			entry := v.Context + "/" + v.Namespace
+			if dirsVerbose {
+				fmt.Printf("%2d  %s\n", i, entry)
+			}
+			entries = append(entries, entry)
+		}
+
+		if !dirsVerbose {
+			fmt.Println(strings.Join(entries, " "))
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(pushCmd)
+	RootCmd.AddCommand(popCmd)
+	RootCmd.AddCommand(dirsCmd)
+
+	addSwitchFlags(pushCmd)
+	popCmd.Flags().BoolVarP(&yesFlag, "yes", "y", false,
+		"return to protected contexts without confirmation")
+	dirsCmd.Flags().BoolVarP(&dirsVerbose, "verbose", "v", false,
+		"print one selection per line, with its index")
+}
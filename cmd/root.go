@@ -21,16 +21,36 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
+	"github.com/jesselang/kcn/internal/config"
+	"github.com/jesselang/kcn/internal/kubectl"
+	"github.com/jesselang/kcn/internal/labels"
+	"github.com/jesselang/kcn/internal/picker"
+	"github.com/jesselang/kcn/internal/recent"
 	"github.com/jesselang/kcn/internal/state"
 )
 
-var cfgFile string
+var (
+	cfgFile         string
+	cfg             config.Config
+	interactiveFlag bool
+	yesFlag         bool
+	exactFlag       bool
+	selectorFlag    string
+)
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
@@ -38,34 +58,261 @@ var RootCmd = &cobra.Command{
 	Short: "Kubernetes context and namespace switcher",
 	Long: `Per-shell context and namespace management for kubectl
 and other CLI programs that use the kubernetes client.`,
-	Args: cobra.RangeArgs(1, 2),
-	Run: func(cmd *cobra.Command, args []string) {
-		st, err := state.ReadState(os.Getenv(envStatePath))
-		if err != nil {
+	Args: cobra.RangeArgs(0, 2),
+	Run:  runSwitch,
+}
+
+// runSwitch selects the context and namespace in args, or picks them
+// interactively.
+func runSwitch(cmd *cobra.Command, args []string) {
+	st := readState()
+
+	var err error
+	if len(selectorFlag) > 0 {
+		if len(args) > 1 {
+			fmt.Fprintln(os.Stderr, "error: only a namespace can be given with --selector")
+			os.Exit(1)
+		}
+
+		args, err = labelArgs(cmd.Context(), st, args)
+		if err == picker.ErrNoTerminal {
+			return
+		} else if err == picker.ErrCanceled {
+			os.Exit(130)
+		} else if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %s\n", err)
 			os.Exit(1)
 		}
+	}
 
-		if err := st.Update(args...); err != nil {
+	if len(args) == 0 || (len(args) == 1 && interactiveFlag) {
+		args, err = pickArgs(cmd.Context(), st, args)
+		if err == picker.ErrNoTerminal {
+			return
+		} else if err == picker.ErrCanceled {
+			os.Exit(130)
+		} else if err != nil {
 			fmt.Fprintf(os.Stderr, "error: %s\n", err)
 			os.Exit(1)
 		}
-	},
+	}
+
+	if err := st.Update(cmd.Context(), args...); err == context.Canceled {
+		os.Exit(130)
+	} else if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+// labelArgs prepends the context selected by --selector to args. Several
+// matches are an error, unless the user is to pick one with --interactive.
+func labelArgs(ctx context.Context, st *state.State, args []string) ([]string, error) {
+	sel := flagSelector()
+
+	if interactiveFlag && len(args) == 0 {
+		contexts, err := st.Labeled(ctx, sel)
+		if err != nil {
+			return nil, err
+		}
+		if len(contexts) > 1 {
+			context, err := pickContext(ctx, st, contexts)
+			if err != nil {
+				return nil, err
+			}
+			return []string{context}, nil
+		}
+	}
+
+	context, err := st.ByLabels(ctx, sel)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{context}, args...), nil
+}
+
+// flagSelector parses --selector, exiting when it's invalid.
+func flagSelector() labels.Selector {
+	sel, err := labels.Parse(selectorFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+
+	return sel
+}
+
+// readState reads the session's state, configured for switching.
+func readState() *state.State {
+	st, err := state.ReadState(os.Getenv(envStatePath), newKubectl())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+
+	return configureState(st)
+}
+
+// readStateOrEmpty reads the session's state like readState, or returns an
+// empty one outside of a session, as in scripts, for commands that don't
+// change it.
+func readStateOrEmpty() *state.State {
+	if len(os.Getenv(envStatePath)) > 0 {
+		return readState()
+	}
+
+	return configureState(state.NewEmpty(newKubectl(), cfg))
+}
+
+func configureState(st *state.State) *state.State {
+	st.Exact = exactFlag || viper.GetBool("exact")
+	st.Config = cfg
+	st.Confirm = func(context string) bool {
+		return yesFlag || confirm(fmt.Sprintf(
+			"switch to protected context %s?", context))
+	}
+	if path, err := recent.DefaultPath(); err == nil {
+		st.Recent = recent.NewStore(path)
+	}
+	st.Audit = auditLog()
+
+	return st
+}
+
+// addSwitchFlags adds the flags of commands that switch contexts.
+func addSwitchFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolVarP(&interactiveFlag, "interactive", "i", false,
+		"pick the namespace interactively when only a context is given")
+	cmd.Flags().BoolVarP(&yesFlag, "yes", "y", false,
+		"switch to protected contexts without confirmation")
+	cmd.Flags().BoolVar(&exactFlag, "exact", false,
+		"only accept exact context and namespace names")
+	addSelectorFlag(cmd, "select the context by its configured labels, like env=stage,region=eu")
+}
+
+// addSelectorFlag adds --selector, completed from the configured labels.
+func addSelectorFlag(cmd *cobra.Command, usage string) {
+	cmd.Flags().StringVarP(&selectorFlag, "selector", "l", "", usage)
+	cmd.RegisterFlagCompletionFunc("selector",
+		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			var pairs []string
+			seen := map[string]bool{}
+			for _, c := range cfg.Contexts {
+				for key, value := range c.Labels {
+					if pair := key + "=" + value; !seen[pair] {
+						seen[pair] = true
+						pairs = append(pairs, pair)
+					}
+				}
+			}
+			sort.Strings(pairs)
+
+			return pairs, cobra.ShellCompDirectiveNoFileComp
+		})
 }
 
 // Execute adds all child commands to the root command sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	if err := RootCmd.Execute(); err != nil {
+	ctx, stop := interruptContext()
+	defer stop()
+
+	RootCmd.SetArgs(historyArgs(os.Args[1:]))
+
+	if err := RootCmd.ExecuteContext(ctx); err != nil {
 		fmt.Println(err)
 		os.Exit(-1)
 	}
 }
 
+// what Ctrl-C does, see setInterrupt
+const (
+	// cancel the context, then exit if kcn is still running shortly after
+	interruptExit int32 = iota
+	// only cancel the context, for commands that stop their children with
+	// it and exit on their own
+	interruptCancel
+	// nothing, while a child in the foreground handles Ctrl-C itself
+	interruptIgnore
+)
+
+var onInterrupt = interruptExit
+
+// setInterrupt changes what Ctrl-C does from then on.
+func setInterrupt(mode int32) {
+	atomic.StoreInt32(&onInterrupt, mode)
+}
+
+// interruptContext returns a context that Ctrl-C cancels, which stops any
+// kubectl command kcn is waiting on. kcn exits if it's still running shortly
+// after, as when waiting for an answer on the terminal, unless setInterrupt
+// said otherwise.
+func interruptContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		for {
+			select {
+			case <-interrupt:
+			case <-stopped:
+				return
+			}
+
+			switch atomic.LoadInt32(&onInterrupt) {
+			case interruptIgnore:
+				continue
+			case interruptCancel:
+				cancel()
+				continue
+			}
+
+			cancel()
+			time.Sleep(2 * time.Second)
+			if atomic.LoadInt32(&onInterrupt) == interruptExit {
+				os.Exit(130)
+			}
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(interrupt)
+		close(stopped)
+		cancel()
+	}
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.kcn.yaml)")
+	RootCmd.PersistentFlags().String("backend", kubectl.BackendNative,
+		"how kubeconfig is read: native or command (runs kubectl)")
+	viper.BindPFlag("backend", RootCmd.PersistentFlags().Lookup("backend"))
+	RootCmd.PersistentFlags().Duration("request-timeout", kubectl.DefaultTimeout,
+		"how long to wait for kubectl, like 10s")
+	viper.BindPFlag("request_timeout", RootCmd.PersistentFlags().Lookup("request-timeout"))
+
+	addSwitchFlags(RootCmd)
+}
+
+// settings that are read from the environment too. Not every key is, as kcn
+// exports variables like KCN_PROTECTED that would be read as settings.
+var envKeys = []string{
+	"backend",
+	"request_timeout",
+	"exact",
+	"gc.max_age",
+	"history.max_depth",
+	"cache.ttl",
+	"prompt.format",
+	"audit.path",
+	"audit.max_size",
+	"audit.max_files",
+	"audit.disabled",
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -74,12 +321,118 @@ func initConfig() {
 		viper.SetConfigFile(cfgFile)
 	}
 
+	viper.SetDefault("gc.max_age", "720h")
+	viper.SetDefault("history.max_depth", 100)
+	viper.SetDefault("cache.ttl", "5m")
+	viper.SetDefault("audit.max_size", 10<<20)
+	viper.SetDefault("audit.max_files", 5)
+
 	viper.SetConfigName(".kcn")            // name of config file (without extension)
 	viper.AddConfigPath(os.Getenv("HOME")) // adding home directory as first search path
-	viper.AutomaticEnv()                   // read in environment variables that match
+	viper.SetEnvPrefix("kcn")              // read KCN_BACKEND and friends
+	// nested keys too, like KCN_CACHE_TTL for cache.ttl
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	for _, key := range envKeys {
+		viper.BindEnv(key)
+	}
+
+	// If a config file is found, read it in. Nothing is printed, as the
+	// output of kcn env is sourced by the shell.
+	if err := viper.ReadInConfig(); err != nil {
+		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
+			fmt.Fprintf(os.Stderr, "kcn: %s\n", err)
+		}
+	}
+
+	if err := viper.Unmarshal(&cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "kcn: invalid config: %s\n", err)
+	}
+}
+
+// historyArgs rewrites -N as @N where it's an argument of kcn or kcn push,
+// which cobra would otherwise parse as shorthand flags. Other commands, flag
+// values and arguments after -- are left alone.
+func historyArgs(args []string) []string {
+	cmd, _, err := RootCmd.Find(args)
+	if err != nil || (cmd != RootCmd && cmd != pushCmd) {
+		return args
+	}
+
+	rewritten := make([]string, 0, len(args))
+	value := false
+	for i, v := range args {
+		if v == "--" {
+			return append(rewritten, args[i:]...)
+		}
+
+		switch {
+		case value:
+			// the value of the previous flag
+			value = false
+		case len(v) > 1 && v[0] == '-' && strings.Trim(v[1:], "0123456789") == "":
+			v = "@" + v[1:]
+		case strings.HasPrefix(v, "-") && !strings.Contains(v, "="):
+			value = takesValue(cmd, v)
+		}
+		rewritten = append(rewritten, v)
+	}
+
+	return rewritten
+}
+
+// takesValue reports whether arg is a flag of cmd followed by a separate
+// value, like --selector or -l, but not -l=... or -lenv=prod.
+func takesValue(cmd *cobra.Command, arg string) bool {
+	var f *pflag.Flag
+	if strings.HasPrefix(arg, "--") {
+		f = cmd.Flags().Lookup(arg[2:])
+		if f == nil {
+			f = cmd.InheritedFlags().Lookup(arg[2:])
+		}
+	} else if len(arg) == 2 {
+		f = cmd.Flags().ShorthandLookup(arg[1:])
+		if f == nil {
+			f = cmd.InheritedFlags().ShorthandLookup(arg[1:])
+		}
+	}
+
+	return f != nil && len(f.NoOptDefVal) == 0
+}
 
-	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err == nil {
-		fmt.Println("Using config file:", viper.ConfigFileUsed())
+// configPath returns the config file in use, or where one would be created.
+func configPath() string {
+	if path := viper.ConfigFileUsed(); len(path) > 0 {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+
+	if cfgFile != "" {
+		return cfgFile
+	}
+
+	return filepath.Join(os.Getenv("HOME"), ".kcn.yaml")
+}
+
+// newKubectl returns the configured kubectl implementation, caching
+// namespace lists unless cache.ttl is zero.
+func newKubectl() kubectl.Kubectl {
+	k, err := kubectl.New(viper.GetString("backend"),
+		viper.GetDuration("request_timeout"))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s\n", err)
+		os.Exit(1)
+	}
+
+	if viper.GetDuration("cache.ttl") <= 0 {
+		return k
 	}
+
+	cached, err := newCached(k)
+	if err != nil {
+		return k
+	}
+	cached.Refresh = refreshInBackground
+
+	return cached
 }
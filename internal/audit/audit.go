@@ -0,0 +1,283 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package audit appends the session's changes of context and namespace to a
+// JSON lines log shared by all sessions, rotated by size, and queries it.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jesselang/kcn/internal/fsutil"
+	"github.com/jesselang/kcn/internal/match"
+)
+
+// actions recorded in the log
+const (
+	ActionSwitch = "switch"
+	ActionSwap   = "swap"
+	ActionJump   = "jump"
+	ActionPop    = "pop"
+	ActionClear  = "clear"
+)
+
+// Log is the audit log file, along with its rotated files path.1 (the
+// newest) to path.MaxFiles.
+type Log struct {
+	Path string
+	// the file is rotated before growing beyond this many bytes; zero never
+	// rotates
+	MaxSize int64
+	// rotated files kept
+	MaxFiles int
+}
+
+// Selection is a context and namespace, and how they were resolved.
+type Selection struct {
+	Context   string `json:"context"`
+	Namespace string `json:"namespace"`
+	// where the namespace came from, like argument or session
+	NamespaceFrom string `json:"namespace_from,omitempty"`
+	Alias         string `json:"alias,omitempty"`
+}
+
+// Entry is one line of the log.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+	Session string    `json:"session"`
+	TTY     string    `json:"tty,omitempty"`
+	// one of the Action constants
+	Action string `json:"action"`
+	// as given to kcn
+	Args []string `json:"args,omitempty"`
+	// nil when nothing was selected
+	Previous *Selection `json:"previous,omitempty"`
+	Current  *Selection `json:"current,omitempty"`
+}
+
+func NewLog(path string, maxSize int64, maxFiles int) *Log {
+	return &Log{Path: path, MaxSize: maxSize, MaxFiles: maxFiles}
+}
+
+// DefaultPath returns where the log is written by default.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "kcn", "audit.jsonl"), nil
+}
+
+// NewEntry returns an entry for action in session, made now by the current
+// user on this host.
+func NewEntry(action, session string) Entry {
+	e := Entry{
+		Time:    time.Now().UTC(),
+		Session: session,
+		TTY:     tty(),
+		Action:  action,
+	}
+
+	if u, err := user.Current(); err == nil {
+		e.User = u.Username
+	} else {
+		e.User = os.Getenv("USER")
+	}
+	e.Host, _ = os.Hostname()
+
+	return e
+}
+
+// Append writes e to the end of the log, rotating it first when it would
+// grow beyond MaxSize.
+func (l *Log) Append(e Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+
+	if err := os.MkdirAll(filepath.Dir(l.Path), 0700); err != nil {
+		return err
+	}
+
+	unlock, err := fsutil.Lock(l.Path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if info, err := os.Stat(l.Path); err == nil && l.MaxSize > 0 &&
+		info.Size() > 0 && info.Size()+int64(len(b)) > l.MaxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(l.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// rotate shifts path.N to path.N+1, dropping the oldest beyond MaxFiles, and
+// moves path to path.1.
+func (l *Log) rotate() error {
+	if l.MaxFiles <= 0 {
+		return os.Remove(l.Path)
+	}
+
+	for _, v := range l.rotated() {
+		if v.n >= l.MaxFiles {
+			if err := os.Remove(v.path); err != nil {
+				return err
+			}
+		}
+	}
+
+	for n := l.MaxFiles - 1; n >= 1; n-- {
+		err := os.Rename(l.rotatedPath(n), l.rotatedPath(n+1))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return os.Rename(l.Path, l.rotatedPath(1))
+}
+
+func (l *Log) rotatedPath(n int) string {
+	return fmt.Sprintf("%s.%d", l.Path, n)
+}
+
+type rotatedFile struct {
+	path string
+	n    int
+}
+
+// rotated returns the rotated files that exist, oldest first.
+func (l *Log) rotated() []rotatedFile {
+	paths, _ := filepath.Glob(l.Path + ".*")
+
+	var files []rotatedFile
+	for _, v := range paths {
+		n, err := strconv.Atoi(strings.TrimPrefix(v, l.Path+"."))
+		if err == nil && n > 0 {
+			files = append(files, rotatedFile{path: v, n: n})
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].n > files[j].n })
+
+	return files
+}
+
+// Read returns the entries matching q from the log and its rotated files,
+// oldest first. Lines that can't be parsed are skipped.
+func (l *Log) Read(q Query) ([]Entry, error) {
+	var paths []string
+	for _, v := range l.rotated() {
+		paths = append(paths, v.path)
+	}
+	paths = append(paths, l.Path)
+
+	var entries []Entry
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1024*1024)
+		for scanner.Scan() {
+			var e Entry
+			if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+				continue
+			}
+			if q.Matches(e) {
+				entries = append(entries, e)
+			}
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return entries, nil
+}
+
+// Query selects entries of the log; zero fields match everything.
+type Query struct {
+	Since time.Time
+	Until time.Time
+	// name, glob or /regexp/ matching the previous or current context
+	Context string
+	Session string
+	User    string
+}
+
+// Matches reports whether e meets every condition of q.
+func (q Query) Matches(e Entry) bool {
+	if !q.Since.IsZero() && e.Time.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !e.Time.Before(q.Until) {
+		return false
+	}
+	if len(q.Session) > 0 && e.Session != q.Session {
+		return false
+	}
+	if len(q.User) > 0 && e.User != q.User {
+		return false
+	}
+
+	if len(q.Context) > 0 {
+		for _, v := range []*Selection{e.Previous, e.Current} {
+			if v != nil && match.Pattern(q.Context, v.Context) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
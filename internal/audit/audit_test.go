@@ -0,0 +1,178 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package audit
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func logFixture(t *testing.T) *Log {
+	dir, err := ioutil.TempDir("", "kcn-audit")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return NewLog(filepath.Join(dir, "kcn", "audit.jsonl"), 0, 3)
+}
+
+func entryFixture(n int, session, context string) Entry {
+	e := NewEntry(ActionSwitch, session)
+	e.Time = time.Date(2020, 1, 1, 0, n, 0, 0, time.UTC)
+	e.Current = &Selection{Context: context, Namespace: "default"}
+	return e
+}
+
+func TestAppend(t *testing.T) {
+	l := logFixture(t)
+
+	entries, err := l.Read(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected an empty log, got %+v", entries)
+	}
+
+	e := entryFixture(0, "kcn-1-a", "alpha-dev")
+	e.Args = []string{"alpha", "default"}
+	if err := l.Append(e); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Append(entryFixture(1, "kcn-1-a", "bravo-stage")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err = l.Read(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	if entries[0].Current.Context != "alpha-dev" || entries[0].Args[0] != "alpha" ||
+		len(entries[0].User) == 0 || !entries[0].Time.Equal(e.Time) {
+		t.Errorf("unexpected first entry %+v", entries[0])
+	}
+
+	info, err := os.Stat(l.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected the log to be private, got %s", info.Mode())
+	}
+}
+
+func TestRotate(t *testing.T) {
+	l := logFixture(t)
+
+	// every entry rotates the log
+	l.MaxSize = 1
+	for n := 0; n < 6; n++ {
+		if err := l.Append(entryFixture(n, "kcn-1-a", "alpha-dev")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for n, expected := range map[int]bool{1: true, 2: true, 3: true, 4: false} {
+		if _, err := os.Stat(l.rotatedPath(n)); (err == nil) != expected {
+			t.Errorf("%s should exist: %t", l.rotatedPath(n), expected)
+		}
+	}
+
+	entries, err := l.Read(Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 4 {
+		t.Fatalf("expected the last 4 entries, got %d", len(entries))
+	}
+	for i, e := range entries {
+		if e.Time.Minute() != i+2 {
+			t.Errorf("expected entries oldest first, got %s at %d", e.Time, i)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	l := logFixture(t)
+
+	for _, e := range []Entry{
+		entryFixture(0, "kcn-1-a", "alpha-dev"),
+		entryFixture(1, "kcn-1-a", "delta-prod"),
+		entryFixture(2, "kcn-2-b", "bravo-stage"),
+		entryFixture(3, "kcn-2-b", "alpha-dev"),
+	} {
+		if err := l.Append(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the switch from delta-prod
+	pop := entryFixture(4, "kcn-1-a", "alpha-dev")
+	pop.Action = ActionPop
+	pop.Previous = &Selection{Context: "delta-prod", Namespace: "default"}
+	if err := l.Append(pop); err != nil {
+		t.Fatal(err)
+	}
+
+	at := func(n int) time.Time { return time.Date(2020, 1, 1, 0, n, 0, 0, time.UTC) }
+	tests := []struct {
+		name     string
+		query    Query
+		expected []int
+	}{
+		{"all", Query{}, []int{0, 1, 2, 3, 4}},
+		{"since", Query{Since: at(3)}, []int{3, 4}},
+		{"until", Query{Until: at(2)}, []int{0, 1}},
+		{"range", Query{Since: at(1), Until: at(3)}, []int{1, 2}},
+		{"session", Query{Session: "kcn-2-b"}, []int{2, 3}},
+		{"context", Query{Context: "*-prod"}, []int{1, 4}},
+		{"context and session", Query{Context: "alpha-dev", Session: "kcn-1-a"}, []int{0, 4}},
+		{"user", Query{User: "nobody-at-all"}, nil},
+	}
+
+	for _, test := range tests {
+		entries, err := l.Read(test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var minutes []int
+		for _, e := range entries {
+			minutes = append(minutes, e.Time.Minute())
+		}
+		if len(minutes) != len(test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, minutes)
+			continue
+		}
+		for i := range minutes {
+			if minutes[i] != test.expected[i] {
+				t.Errorf("%s: expected %v, got %v", test.name, test.expected, minutes)
+				break
+			}
+		}
+	}
+}
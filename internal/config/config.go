@@ -0,0 +1,305 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package config describes kcn's configuration file, ~/.kcn.yaml.
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/jesselang/kcn/internal/fsutil"
+	"github.com/jesselang/kcn/internal/match"
+)
+
+// kubectl verbs that ask for confirmation in a protected context, unless
+// configured otherwise
+var DefaultProtectedVerbs = []string{"apply", "delete", "scale", "edit", "patch"}
+
+type Config struct {
+	// short names for a context and optional namespace
+	Aliases map[string]Alias `mapstructure:"aliases"`
+	// context names or patterns that require confirmation to switch to
+	Protected []string `mapstructure:"protected"`
+	// kubectl verbs that require confirmation in a protected context
+	ProtectedVerbs []string `mapstructure:"protected_verbs"`
+	// garbage collection of state files
+	GC      GC      `mapstructure:"gc"`
+	History History `mapstructure:"history"`
+	Cache   Cache   `mapstructure:"cache"`
+	// settings for contexts matching a name or pattern, the first match wins;
+	// labels of every match are merged
+	Contexts []Context `mapstructure:"contexts"`
+	Prompt   Prompt    `mapstructure:"prompt"`
+	Audit    Audit     `mapstructure:"audit"`
+}
+
+type Audit struct {
+	// log file, the default when empty
+	Path string `mapstructure:"path"`
+	// the log is rotated before growing beyond this many bytes
+	MaxSize int64 `mapstructure:"max_size"`
+	// rotated files kept
+	MaxFiles int  `mapstructure:"max_files"`
+	Disabled bool `mapstructure:"disabled"`
+}
+
+type Prompt struct {
+	// go template rendered by kcn prompt
+	Format string `mapstructure:"format"`
+}
+
+type Context struct {
+	// context name or pattern
+	Name string `mapstructure:"name"`
+	// selected when none is given, unless the session or kubeconfig has one
+	Namespace string `mapstructure:"namespace"`
+	// of the context in kcn prompt, like red or 208
+	Color string `mapstructure:"color"`
+	// arbitrary key/values, like env: prod, for label selectors
+	Labels map[string]string `mapstructure:"labels"`
+}
+
+type Cache struct {
+	// cached namespace lists older than this are refreshed in the background;
+	// zero disables the cache
+	TTL time.Duration `mapstructure:"ttl"`
+}
+
+type History struct {
+	// oldest elements beyond this depth are evicted; zero keeps all
+	MaxDepth int `mapstructure:"max_depth"`
+}
+
+type GC struct {
+	// state files unused for longer are collected, even if their shell is
+	// still running; zero disables collection by age
+	MaxAge time.Duration `mapstructure:"max_age"`
+}
+
+type Alias struct {
+	Context   string `mapstructure:"context" yaml:"context"`
+	Namespace string `mapstructure:"namespace" yaml:"namespace,omitempty"`
+}
+
+// IsProtected reports whether context matches one of the protected patterns.
+func (c *Config) IsProtected(context string) bool {
+	return len(context) > 0 && match.AnyPattern(c.Protected, context)
+}
+
+// ContextNamespace returns the namespace configured for context, if any.
+func (c *Config) ContextNamespace(context string) string {
+	for _, v := range c.Contexts {
+		if match.Pattern(v.Name, context) && len(v.Namespace) > 0 {
+			return v.Namespace
+		}
+	}
+
+	return ""
+}
+
+// ContextColor returns the prompt color configured for context, if any.
+func (c *Config) ContextColor(context string) string {
+	for _, v := range c.Contexts {
+		if match.Pattern(v.Name, context) && len(v.Color) > 0 {
+			return v.Color
+		}
+	}
+
+	return ""
+}
+
+// ContextLabels returns the labels configured for context, merged from every
+// matching entry; earlier entries win.
+func (c *Config) ContextLabels(context string) map[string]string {
+	labels := map[string]string{}
+	if len(context) == 0 {
+		return labels
+	}
+
+	for _, v := range c.Contexts {
+		if !match.Pattern(v.Name, context) {
+			continue
+		}
+		for key, value := range v.Labels {
+			if _, ok := labels[key]; !ok {
+				labels[key] = value
+			}
+		}
+	}
+
+	return labels
+}
+
+// LabelKeys returns every label key configured for any context, sorted.
+func (c *Config) LabelKeys() []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, v := range c.Contexts {
+		for key := range v.Labels {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// IsProtectedVerb reports whether the kubectl verb requires confirmation in a
+// protected context.
+func (c *Config) IsProtectedVerb(verb string) bool {
+	verbs := c.ProtectedVerbs
+	if verbs == nil {
+		verbs = DefaultProtectedVerbs
+	}
+
+	for _, v := range verbs {
+		if v == verb {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetAlias adds or replaces an alias in the YAML config file at path,
+// creating the file if needed. Names are lowercased, as they're read.
+func SetAlias(path, name string, alias Alias) error {
+	return edit(path, func(doc *yaml.Node) error {
+		var value yaml.Node
+		if err := value.Encode(alias); err != nil {
+			return err
+		}
+
+		aliases := section(doc, "aliases")
+		if i := find(aliases, name); i >= 0 {
+			aliases.Content[i].Value = strings.ToLower(name)
+			aliases.Content[i+1] = &value
+			return nil
+		}
+
+		aliases.Content = append(aliases.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: strings.ToLower(name)}, &value)
+		return nil
+	})
+}
+
+// RemoveAlias removes an alias from the YAML config file at path.
+func RemoveAlias(path, name string) error {
+	return edit(path, func(doc *yaml.Node) error {
+		aliases := section(doc, "aliases")
+
+		i := find(aliases, name)
+		if i < 0 {
+			return fmt.Errorf("alias %s not found", name)
+		}
+		aliases.Content = append(aliases.Content[:i], aliases.Content[i+2:]...)
+
+		return nil
+	})
+}
+
+// edit rewrites the YAML file at path, keeping the comments, order and style
+// of everything fn doesn't change in the document's top-level mapping.
+func edit(path string, fn func(*yaml.Node) error) error {
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+	default:
+		return fmt.Errorf("can only edit YAML config files, not %s", path)
+	}
+
+	// a symlinked config, as from a dotfiles repository, is edited in place
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	var doc yaml.Node
+	b, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return fmt.Errorf("could not parse %s: %s", path, err)
+	}
+
+	if len(doc.Content) == 0 {
+		doc = yaml.Node{
+			Kind:    yaml.DocumentNode,
+			Content: []*yaml.Node{{Kind: yaml.MappingNode}},
+		}
+	}
+	if doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("could not edit %s, it isn't a mapping", path)
+	}
+
+	if err := fn(doc.Content[0]); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// section returns the mapping stored under key in m, adding an empty one when
+// there isn't one.
+func section(m *yaml.Node, key string) *yaml.Node {
+	if i := find(m, key); i >= 0 {
+		if m.Content[i+1].Kind != yaml.MappingNode {
+			m.Content[i+1] = &yaml.Node{Kind: yaml.MappingNode}
+		}
+		return m.Content[i+1]
+	}
+
+	value := &yaml.Node{Kind: yaml.MappingNode}
+	m.Content = append(m.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, value)
+	return value
+}
+
+// find returns the index of key in the mapping m, ignoring case as viper
+// does, or -1 when it's missing. Its value follows at the next index.
+func find(m *yaml.Node, key string) int {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if strings.EqualFold(m.Content[i].Value, key) {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,137 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEditAliases(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kcn-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, ".kcn.yaml")
+	initial := "# shared by the team\nexact: true # no fuzzy matching\n"
+	if err := ioutil.WriteFile(path, []byte(initial), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err = SetAlias(path, "Prod", Alias{Context: "delta-prod", Namespace: "app-z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SetAlias(path, "dev", Alias{Context: "alpha-dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = SetAlias(path, "prod", Alias{Context: "delta-prod"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveAlias(path, "DEV"); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveAlias(path, "dev"); err == nil {
+		t.Error("removing a missing alias should fail")
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := initial + "aliases:\n  prod:\n    context: delta-prod\n"
+	if string(b) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b)
+	}
+}
+
+func TestEditNonYAML(t *testing.T) {
+	if err := SetAlias("/nonexistent/.kcn.json", "dev", Alias{}); err == nil {
+		t.Error("editing a non-YAML config should fail")
+	}
+}
+
+func TestProtected(t *testing.T) {
+	c := Config{
+		Protected: []string{"delta-prod", "/-prod$/", "gke_*_prod", "arn:*/prod*"},
+	}
+
+	cases := map[string]bool{
+		"delta-prod":                             true,
+		"echo-prod":                              true,
+		"gke_acme_prod":                          true,
+		"alpha-dev":                              false,
+		"":                                       false,
+		"gke_acme_us-east1_prod":                 true,
+		"arn:aws:eks:us-east-1:123:cluster/prod": true,
+		"arn:aws:eks:us-east-1:123:cluster/stage": false,
+	}
+
+	for context, expected := range cases {
+		if c.IsProtected(context) != expected {
+			t.Errorf("IsProtected(%q) should be %t", context, expected)
+		}
+	}
+
+	if !c.IsProtectedVerb("delete") || c.IsProtectedVerb("get") {
+		t.Error("default protected verbs should include delete, not get")
+	}
+
+	c.ProtectedVerbs = []string{"drain"}
+	if c.IsProtectedVerb("delete") || !c.IsProtectedVerb("drain") {
+		t.Error("configured protected verbs should replace the defaults")
+	}
+}
+
+func TestContextLabels(t *testing.T) {
+	c := Config{
+		Contexts: []Context{
+			{Name: "delta-prod", Labels: map[string]string{"team": "payments"}},
+			{Name: "/-prod$/", Labels: map[string]string{"env": "prod", "team": "platform"}},
+			{Name: "*", Labels: map[string]string{"region": "eu"}},
+		},
+	}
+
+	labels := c.ContextLabels("delta-prod")
+	expected := map[string]string{"env": "prod", "team": "payments", "region": "eu"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("ContextLabels(delta-prod) = %v, expected %v", labels, expected)
+	}
+
+	labels = c.ContextLabels("alpha-dev")
+	expected = map[string]string{"region": "eu"}
+	if !reflect.DeepEqual(labels, expected) {
+		t.Errorf("ContextLabels(alpha-dev) = %v, expected %v", labels, expected)
+	}
+
+	keys := c.LabelKeys()
+	if !reflect.DeepEqual(keys, []string{"env", "region", "team"}) {
+		t.Errorf("LabelKeys() = %v", keys)
+	}
+}
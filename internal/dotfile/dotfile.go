@@ -0,0 +1,137 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package dotfile finds the .kcn files that select a context and namespace
+// for a directory tree, and tracks which of them the user trusts.
+package dotfile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// names of dotfiles, in the order they're looked for in each directory
+var Names = []string{".kcn", ".kcn.yaml"}
+
+// File is the selection made by a dotfile, either as YAML:
+//
+//	context: alpha-dev
+//	namespace: app-a
+//
+// or as a single line of the form "<context> [namespace]".
+type File struct {
+	Path      string `yaml:"-"`
+	Context   string `yaml:"context"`
+	Namespace string `yaml:"namespace"`
+	// hash of the file's contents, for checking trust
+	Hash string `yaml:"-"`
+}
+
+// Args returns the file's selection as arguments to State.Update.
+func (f *File) Args() []string {
+	if len(f.Namespace) == 0 {
+		return []string{f.Context}
+	}
+
+	return []string{f.Context, f.Namespace}
+}
+
+// Find returns the path of the nearest dotfile in dir or its ancestors, or
+// an empty string when there is none. Files listed in ignore, like kcn's own
+// ~/.kcn.yaml config file, are skipped.
+func Find(dir string, ignore ...string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	skip := map[string]bool{}
+	for _, v := range ignore {
+		if abs, err := filepath.Abs(v); err == nil {
+			skip[abs] = true
+		}
+	}
+
+	for {
+		for _, name := range Names {
+			path := filepath.Join(dir, name)
+			if skip[path] {
+				continue
+			}
+
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads the dotfile at path.
+func Load(path string) (*File, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{}
+	if err := yaml.Unmarshal(b, f); err != nil {
+		// not a mapping, try a single "<context> [namespace]" line
+		fields := strings.Fields(string(b))
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("could not parse %s: %s", path, err)
+		}
+
+		f = &File{Context: fields[0]}
+		if len(fields) > 1 {
+			f.Namespace = fields[1]
+		}
+	}
+
+	if len(f.Context) == 0 {
+		return nil, errors.New(path + " does not select a context")
+	}
+
+	f.Path, err = filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	f.Hash = hash(b)
+
+	return f, nil
+}
+
+func hash(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,177 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dotfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "kcn-dotfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	// resolve symlinks, like /tmp on macOS, so paths compare equal
+	dir, err = filepath.EvalSymlinks(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestFind(t *testing.T) {
+	dir := tempDir(t)
+	service := filepath.Join(dir, "service")
+	nested := filepath.Join(service, "cmd", "server")
+	if err := os.MkdirAll(nested, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	top := filepath.Join(dir, ".kcn.yaml")
+	file := filepath.Join(service, ".kcn")
+	for _, v := range []string{top, file} {
+		if err := ioutil.WriteFile(v, []byte("alpha-dev"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		dir      string
+		ignore   []string
+		expected string
+	}{
+		{nested, nil, file},
+		{service, nil, file},
+		{dir, nil, top},
+		{dir, []string{top}, ""},
+	}
+
+	for _, c := range cases {
+		found, err := Find(c.dir, c.ignore...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the search continues above the temporary directory, so only
+		// results within it are checked
+		if len(c.expected) == 0 && filepath.Dir(found) != dir {
+			continue
+		}
+		if found != c.expected {
+			t.Errorf("Find(%s) expected %q, got %q", c.dir, c.expected, found)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := tempDir(t)
+
+	cases := []struct {
+		contents string
+		args     []string
+		ok       bool
+	}{
+		{"context: alpha-dev\nnamespace: app-a\n", []string{"alpha-dev", "app-a"}, true},
+		{"context: alpha-dev\n", []string{"alpha-dev"}, true},
+		{"alpha-dev app-a\n", []string{"alpha-dev", "app-a"}, true},
+		{"alpha-dev\n", []string{"alpha-dev"}, true},
+		{"namespace: app-a\n", nil, false},
+		{"a b c\n", nil, false},
+		{"", nil, false},
+	}
+
+	for _, c := range cases {
+		path := filepath.Join(dir, ".kcn")
+		if err := ioutil.WriteFile(path, []byte(c.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		f, err := Load(path)
+		if !c.ok {
+			if err == nil {
+				t.Errorf("%q should fail to load", c.contents)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %s", c.contents, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(f.Args(), c.args) {
+			t.Errorf("%q: expected %v, got %v", c.contents, c.args, f.Args())
+		}
+	}
+}
+
+func TestTrust(t *testing.T) {
+	dir := tempDir(t)
+	trust := &Trust{path: filepath.Join(dir, "kcn", "allowed.json")}
+
+	path := filepath.Join(dir, ".kcn")
+	if err := ioutil.WriteFile(path, []byte("alpha-dev"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trust.Allowed(f) {
+		t.Error("dotfile should not be allowed before kcn allow")
+	}
+
+	if err := trust.Allow(f); err != nil {
+		t.Fatal(err)
+	}
+	if !trust.Allowed(f) {
+		t.Error("dotfile should be allowed")
+	}
+
+	// changed contents must be allowed again
+	if err := ioutil.WriteFile(path, []byte("delta-prod"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if trust.Allowed(changed) {
+		t.Error("changed dotfile should not be allowed")
+	}
+
+	if err := trust.Allow(changed); err != nil {
+		t.Fatal(err)
+	}
+	if err := trust.Deny(path); err != nil {
+		t.Fatal(err)
+	}
+	if trust.Allowed(changed) {
+		t.Error("denied dotfile should not be allowed")
+	}
+}
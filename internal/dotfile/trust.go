@@ -0,0 +1,114 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dotfile
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/jesselang/kcn/internal/fsutil"
+)
+
+// Trust records which dotfiles the user allowed, by path and content hash,
+// so a dotfile that changes has to be allowed again.
+type Trust struct {
+	path string
+}
+
+// NewTrust returns the trust store kept in the user's config directory.
+func NewTrust() (*Trust, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Trust{path: filepath.Join(dir, "kcn", "allowed.json")}, nil
+}
+
+// Allowed reports whether f was allowed with its current contents.
+func (t *Trust) Allowed(f *File) bool {
+	allowed, err := t.read()
+	if err != nil {
+		return false
+	}
+
+	return allowed[f.Path] == f.Hash
+}
+
+// Allow trusts f with its current contents.
+func (t *Trust) Allow(f *File) error {
+	return t.modify(func(allowed map[string]string) {
+		allowed[f.Path] = f.Hash
+	})
+}
+
+// Deny stops trusting the dotfile at path.
+func (t *Trust) Deny(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+
+	return t.modify(func(allowed map[string]string) {
+		delete(allowed, path)
+	})
+}
+
+func (t *Trust) read() (map[string]string, error) {
+	allowed := map[string]string{}
+
+	b, err := ioutil.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return allowed, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return allowed, json.Unmarshal(b, &allowed)
+}
+
+func (t *Trust) modify(fn func(map[string]string)) error {
+	if err := os.MkdirAll(filepath.Dir(t.path), 0700); err != nil {
+		return err
+	}
+
+	unlock, err := fsutil.Lock(t.path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	allowed, err := t.read()
+	if err != nil {
+		return err
+	}
+
+	fn(allowed)
+
+	b, err := json.MarshalIndent(allowed, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(t.path, b, 0600)
+}
@@ -0,0 +1,121 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package fsutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "kcn-fsutil")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return dir
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := tempDir(t)
+	path := filepath.Join(dir, "file")
+
+	for _, v := range []string{"first, longer contents", "second"} {
+		if err := WriteFile(path, []byte(v), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != v {
+			t.Errorf("expected %q, got %q", v, b)
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// no temporary files are left behind
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("expected only the written file, got %d files", len(files))
+	}
+}
+
+func TestLock(t *testing.T) {
+	dir := tempDir(t)
+	lock := filepath.Join(dir, "lock")
+	path := filepath.Join(dir, "counter")
+
+	if err := WriteFile(path, []byte("0"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// without the lock, concurrent read-modify-writes would lose increments
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			unlock, err := Lock(lock)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer unlock()
+
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n, _ := strconv.Atoi(string(b))
+			if err := WriteFile(path, []byte(strconv.Itoa(n+1)), 0600); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "20" {
+		t.Errorf("expected 20 increments, got %s", b)
+	}
+}
@@ -0,0 +1,238 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package kubeconfig reads and merges kubeconfig files the same way kubectl
+// does, without shelling out to kubectl.
+package kubeconfig
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/jesselang/kcn/internal/fsutil"
+)
+
+const (
+	// EnvKubeconfig is the standard variable listing kubeconfig files
+	EnvKubeconfig = "KUBECONFIG"
+	// EnvOriginal holds the user's kubeconfig list from before kcn layered
+	// its per-session file on top of it
+	EnvOriginal = "KCN_KUBECONFIG"
+)
+
+type Config struct {
+	APIVersion     string         `yaml:"apiVersion,omitempty" json:"apiVersion,omitempty"`
+	Kind           string         `yaml:"kind,omitempty" json:"kind,omitempty"`
+	CurrentContext string         `yaml:"current-context" json:"current-context"`
+	Clusters       []NamedCluster `yaml:"clusters,omitempty" json:"clusters,omitempty"`
+	Contexts       []NamedContext `yaml:"contexts,omitempty" json:"contexts,omitempty"`
+	Users          []NamedUser    `yaml:"users,omitempty" json:"users,omitempty"`
+}
+
+type NamedCluster struct {
+	Name    string  `yaml:"name" json:"name"`
+	Cluster Cluster `yaml:"cluster" json:"cluster"`
+}
+
+type Cluster struct {
+	Server string `yaml:"server,omitempty" json:"server,omitempty"`
+}
+
+type NamedContext struct {
+	Name    string  `yaml:"name" json:"name"`
+	Context Context `yaml:"context" json:"context"`
+}
+
+type Context struct {
+	Cluster   string `yaml:"cluster" json:"cluster"`
+	User      string `yaml:"user" json:"user"`
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+}
+
+// only the name of a user is of interest, credentials are never read
+type NamedUser struct {
+	Name string `yaml:"name" json:"name"`
+}
+
+// Paths returns the user's kubeconfig files in precedence order, ignoring any
+// per-session file layered on top by kcn.
+func Paths() []string {
+	return SplitPaths(Original())
+}
+
+// Original returns the user's kubeconfig list, from $KCN_KUBECONFIG,
+// $KUBECONFIG or the default of ~/.kube/config.
+func Original() string {
+	if env := os.Getenv(EnvOriginal); len(env) > 0 {
+		return env
+	}
+
+	if env := os.Getenv(EnvKubeconfig); len(env) > 0 {
+		return env
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".kube", "config")
+}
+
+// Layer returns a $KUBECONFIG value with path taking precedence over the
+// user's original kubeconfig list.
+func Layer(path string) string {
+	orig := Original()
+	if len(orig) == 0 {
+		return path
+	}
+
+	return path + string(filepath.ListSeparator) + orig
+}
+
+// SplitPaths splits a $KUBECONFIG style list, dropping empty and duplicate
+// entries.
+func SplitPaths(list string) []string {
+	var paths []string
+	seen := map[string]bool{}
+	for _, p := range filepath.SplitList(list) {
+		if len(p) == 0 || seen[p] {
+			continue
+		}
+		seen[p] = true
+		paths = append(paths, p)
+	}
+
+	return paths
+}
+
+// Load reads and merges the given kubeconfig files. The first file to set
+// current-context wins, and the first file to define a named cluster, context
+// or user wins. Missing files are skipped, as kubectl does.
+func Load(paths ...string) (*Config, error) {
+	merged := &Config{}
+
+	for _, path := range paths {
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		var c Config
+		if err := yaml.Unmarshal(b, &c); err != nil {
+			return nil, fmt.Errorf("could not parse %s: %s", path, err)
+		}
+
+		merged.merge(&c)
+	}
+
+	return merged, nil
+}
+
+// Write saves c to path, readable only by the user.
+func Write(path string, c *Config) error {
+	b, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(path, b, 0600)
+}
+
+func (c *Config) merge(other *Config) {
+	if len(c.CurrentContext) == 0 {
+		c.CurrentContext = other.CurrentContext
+	}
+
+	for _, v := range other.Clusters {
+		if _, ok := c.Cluster(v.Name); !ok {
+			c.Clusters = append(c.Clusters, v)
+		}
+	}
+
+	for _, v := range other.Contexts {
+		if _, ok := c.Context(v.Name); !ok {
+			c.Contexts = append(c.Contexts, v)
+		}
+	}
+
+	for _, v := range other.Users {
+		if !c.hasUser(v.Name) {
+			c.Users = append(c.Users, v)
+		}
+	}
+}
+
+// ContextNames returns context names in the order they were defined.
+func (c *Config) ContextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for _, v := range c.Contexts {
+		names = append(names, v.Name)
+	}
+
+	return names
+}
+
+func (c *Config) Context(name string) (*Context, bool) {
+	for i := range c.Contexts {
+		if c.Contexts[i].Name == name {
+			return &c.Contexts[i].Context, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *Config) Cluster(name string) (*Cluster, bool) {
+	for i := range c.Clusters {
+		if c.Clusters[i].Name == name {
+			return &c.Clusters[i].Cluster, true
+		}
+	}
+
+	return nil, false
+}
+
+func (c *Config) hasUser(name string) bool {
+	for _, v := range c.Users {
+		if v.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Current returns the name of the current context.
+func (c *Config) Current() (string, error) {
+	if len(c.CurrentContext) == 0 {
+		return "", errors.New("current-context is not set")
+	}
+
+	return c.CurrentContext, nil
+}
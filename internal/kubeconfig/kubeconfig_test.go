@@ -0,0 +1,212 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kubeconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const firstFixture = `
+apiVersion: v1
+kind: Config
+contexts:
+- name: alpha-dev
+  context:
+    cluster: alpha
+    user: alpha-admin
+    namespace: app-a
+clusters:
+- name: alpha
+  cluster:
+    server: https://alpha.example.com
+users:
+- name: alpha-admin
+  user:
+    token: secret
+`
+
+const secondFixture = `
+apiVersion: v1
+kind: Config
+current-context: bravo-stage
+contexts:
+- name: alpha-dev
+  context:
+    cluster: other
+    user: other
+- name: bravo-stage
+  context:
+    cluster: bravo
+    user: bravo-admin
+clusters:
+- name: bravo
+  cluster:
+    server: https://bravo.example.com
+`
+
+func writeFixtures(t *testing.T, fixtures ...string) []string {
+	dir, err := ioutil.TempDir("", "kcn-kubeconfig")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	var paths []string
+	for i, v := range fixtures {
+		path := filepath.Join(dir, string(rune('a'+i)))
+		if err := ioutil.WriteFile(path, []byte(v), 0600); err != nil {
+			t.Fatal(err)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths
+}
+
+func TestLoadMerge(t *testing.T) {
+	paths := writeFixtures(t, firstFixture, secondFixture)
+	paths = append(paths, filepath.Join(filepath.Dir(paths[0]), "missing"))
+
+	c, err := Load(paths...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.CurrentContext != "bravo-stage" {
+		t.Errorf("current-context should come from second file, got %s",
+			c.CurrentContext)
+	}
+
+	names := c.ContextNames()
+	if !reflect.DeepEqual(names, []string{"alpha-dev", "bravo-stage"}) {
+		t.Errorf("unexpected context names %v", names)
+	}
+
+	ctx, ok := c.Context("alpha-dev")
+	if !ok {
+		t.Fatal("alpha-dev context not found")
+	}
+	if ctx.Cluster != "alpha" || ctx.Namespace != "app-a" {
+		t.Errorf("first definition of alpha-dev should win, got %+v", *ctx)
+	}
+
+	cluster, ok := c.Cluster("bravo")
+	if !ok || cluster.Server != "https://bravo.example.com" {
+		t.Errorf("bravo cluster not merged, got %+v", cluster)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	paths := writeFixtures(t, "contexts: {")
+
+	if _, err := Load(paths...); err == nil {
+		t.Error("invalid kubeconfig should fail")
+	}
+}
+
+func TestSplitPaths(t *testing.T) {
+	sep := string(filepath.ListSeparator)
+	paths := SplitPaths("a" + sep + sep + "b" + sep + "a")
+
+	if !reflect.DeepEqual(paths, []string{"a", "b"}) {
+		t.Errorf("unexpected paths %v", paths)
+	}
+}
+
+func TestCurrentUnset(t *testing.T) {
+	var c Config
+	if _, err := c.Current(); err == nil {
+		t.Error("unset current-context should fail")
+	}
+}
+
+// setenv sets key to value for the rest of the test, unsetting it when value
+// is empty.
+func setenv(t *testing.T, key, value string) {
+	old, ok := os.LookupEnv(key)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+
+	if len(value) == 0 {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, value)
+	}
+}
+
+func TestOriginal(t *testing.T) {
+	sep := string(filepath.ListSeparator)
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		original   string
+		kubeconfig string
+		expected   string
+	}{
+		{"", "", filepath.Join(home, ".kube", "config")},
+		{"", "a" + sep + "b", "a" + sep + "b"},
+		{"b", "session" + sep + "b", "b"},
+	}
+
+	for _, c := range cases {
+		setenv(t, EnvOriginal, c.original)
+		setenv(t, EnvKubeconfig, c.kubeconfig)
+
+		if orig := Original(); orig != c.expected {
+			t.Errorf("Original() with %s=%q and %s=%q = %q, expected %q", EnvOriginal,
+				c.original, EnvKubeconfig, c.kubeconfig, orig, c.expected)
+		}
+	}
+}
+
+func TestLayer(t *testing.T) {
+	sep := string(filepath.ListSeparator)
+	setenv(t, EnvOriginal, "")
+	setenv(t, EnvKubeconfig, "a"+sep+"b")
+
+	layered := Layer("session")
+	if layered != "session"+sep+"a"+sep+"b" {
+		t.Errorf("expected the session file first, got %q", layered)
+	}
+
+	// once layered, the original list is kept apart so that layering again
+	// doesn't repeat the session file
+	setenv(t, EnvOriginal, Original())
+	setenv(t, EnvKubeconfig, layered)
+	if again := Layer("session"); again != layered {
+		t.Errorf("expected %q when layered again, got %q", layered, again)
+	}
+	if paths := Paths(); !reflect.DeepEqual(paths, []string{"a", "b"}) {
+		t.Errorf("expected the original paths without the session file, got %v", paths)
+	}
+}
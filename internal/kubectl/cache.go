@@ -0,0 +1,217 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kubectl
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jesselang/kcn/internal/fsutil"
+)
+
+// Refresher is implemented by caching implementations, so that a namespace
+// missing from a cached list can be looked up again.
+type Refresher interface {
+	RefreshNamespaceList(ctx context.Context, context string) ([]string, error)
+}
+
+// Cached serves namespace lists from files in Dir, so switching doesn't wait
+// on the API server. Entries are keyed on the context's name and its
+// cluster's server, so a context pointed at another cluster isn't served the
+// old cluster's namespaces.
+type Cached struct {
+	Kubectl
+
+	Dir string
+	// entries older than TTL are refreshed
+	TTL time.Duration
+	// called to refresh a stale entry in the background, while the stale list
+	// is served; stale entries are refreshed before returning when nil
+	Refresh func(context string)
+}
+
+// CacheEntry is a cached namespace list.
+type CacheEntry struct {
+	Context    string    `json:"context"`
+	Server     string    `json:"server"`
+	Namespaces []string  `json:"namespaces"`
+	Time       time.Time `json:"time"`
+}
+
+func NewCached(k Kubectl, dir string, ttl time.Duration) *Cached {
+	return &Cached{Kubectl: k, Dir: dir, TTL: ttl}
+}
+
+// CacheDir returns the default directory of cached namespace lists.
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "kcn", "namespaces"), nil
+}
+
+// a background refresh of an entry isn't started again for this long, while
+// it runs or after it failed; replaced in tests
+var refreshBackoff = time.Minute
+
+func (k *Cached) GetNamespaceList(ctx context.Context, context string) ([]string, error) {
+	path := k.path(context, k.server(ctx, context))
+
+	var entry CacheEntry
+	if err := readEntry(path, &entry); err != nil {
+		return k.RefreshNamespaceList(ctx, context)
+	}
+
+	if time.Since(entry.Time) > k.TTL {
+		if k.Refresh == nil {
+			return k.RefreshNamespaceList(ctx, context)
+		}
+		if claimRefresh(path) {
+			k.Refresh(context)
+		}
+	}
+
+	return entry.Namespaces, nil
+}
+
+// claimRefresh reports whether a background refresh of the entry at path
+// should start, marking one as started. Only one starts per refreshBackoff,
+// so an unreachable cluster isn't asked again on every switch.
+func claimRefresh(path string) bool {
+	marker := path + ".refresh"
+	if info, err := os.Stat(marker); err == nil {
+		if time.Since(info.ModTime()) < refreshBackoff {
+			return false
+		}
+		os.Remove(marker)
+	}
+
+	// only one of several processes creates the marker
+	f, err := os.OpenFile(marker, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return false
+	}
+	f.Close()
+
+	return true
+}
+
+// RefreshNamespaceList looks up the namespace list for context, bypassing and
+// updating its cache entry.
+func (k *Cached) RefreshNamespaceList(ctx context.Context, context string) ([]string, error) {
+	namespaces, err := k.Kubectl.GetNamespaceList(ctx, context)
+	if err != nil {
+		return namespaces, err
+	}
+
+	entry := CacheEntry{
+		Context:    context,
+		Server:     k.server(ctx, context),
+		Namespaces: namespaces,
+		Time:       time.Now(),
+	}
+
+	b, err := json.Marshal(entry)
+	if err != nil {
+		return namespaces, err
+	}
+
+	if err := os.MkdirAll(k.Dir, 0700); err != nil {
+		return namespaces, err
+	}
+
+	path := k.path(context, entry.Server)
+	if err := fsutil.WriteFile(path, b, 0600); err != nil {
+		return namespaces, err
+	}
+	os.Remove(path + ".refresh")
+
+	return namespaces, nil
+}
+
+// Entries returns the cached namespace lists, ordered by context.
+func (k *Cached) Entries() ([]CacheEntry, error) {
+	files, err := ioutil.ReadDir(k.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var entries []CacheEntry
+	for _, v := range files {
+		if !strings.HasSuffix(v.Name(), ".json") {
+			continue
+		}
+
+		var entry CacheEntry
+		if err := readEntry(filepath.Join(k.Dir, v.Name()), &entry); err != nil {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Context < entries[j].Context
+	})
+
+	return entries, nil
+}
+
+// Clear removes all cached namespace lists.
+func (k *Cached) Clear() error {
+	return os.RemoveAll(k.Dir)
+}
+
+func readEntry(path string, entry *CacheEntry) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(b, entry)
+}
+
+// server returns the server of context's cluster, or nothing when the context
+// can't be looked up.
+func (k *Cached) server(ctx context.Context, context string) string {
+	details, err := k.Kubectl.GetContext(ctx, context)
+	if err != nil {
+		return ""
+	}
+
+	return details.Server
+}
+
+func (k *Cached) path(context, server string) string {
+	sum := sha256.Sum256([]byte(context + "\x00" + server))
+	return filepath.Join(k.Dir, hex.EncodeToString(sum[:16])+".json")
+}
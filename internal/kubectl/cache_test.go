@@ -0,0 +1,147 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kubectl
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// counting counts the namespace lists looked up, and can point a context at
+// another server
+type counting struct {
+	Kubectl
+	lookups int
+	server  string
+}
+
+func (k *counting) GetContext(ctx context.Context, name string) (*Context, error) {
+	details, err := k.Kubectl.GetContext(ctx, name)
+	if err == nil && len(k.server) > 0 {
+		details.Server = k.server
+	}
+	return details, err
+}
+
+func (k *counting) GetNamespaceList(ctx context.Context, context string) ([]string, error) {
+	k.lookups++
+	return k.Kubectl.GetNamespaceList(ctx, context)
+}
+
+func TestCached(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kcn-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	ctx := context.Background()
+	inner := &counting{Kubectl: NewMock()}
+	k := NewCached(inner, dir, time.Hour)
+
+	var refreshed []string
+	k.Refresh = func(context string) {
+		refreshed = append(refreshed, context)
+	}
+
+	expected, _ := NewMock().GetNamespaceList(ctx, "alpha-dev")
+	for i := 0; i < 2; i++ {
+		list, err := k.GetNamespaceList(ctx, "alpha-dev")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(list, expected) {
+			t.Errorf("expected %v, got %v", expected, list)
+		}
+	}
+	if inner.lookups != 1 {
+		t.Errorf("expected the second list to be cached, got %d lookups", inner.lookups)
+	}
+
+	// stale entries are served, and refreshed in the background
+	k.TTL = 0
+	if _, err := k.GetNamespaceList(ctx, "alpha-dev"); err != nil {
+		t.Fatal(err)
+	}
+	if inner.lookups != 1 || !reflect.DeepEqual(refreshed, []string{"alpha-dev"}) {
+		t.Errorf("expected a background refresh, got %d lookups, %v", inner.lookups, refreshed)
+	}
+
+	// while it runs, or once it failed, no other is started
+	if _, err := k.GetNamespaceList(ctx, "alpha-dev"); err != nil {
+		t.Fatal(err)
+	}
+	if len(refreshed) != 1 {
+		t.Errorf("expected a single background refresh, got %v", refreshed)
+	}
+
+	// until the backoff passes
+	defer func(backoff time.Duration) { refreshBackoff = backoff }(refreshBackoff)
+	refreshBackoff = 0
+	if _, err := k.GetNamespaceList(ctx, "alpha-dev"); err != nil {
+		t.Fatal(err)
+	}
+	if len(refreshed) != 2 {
+		t.Errorf("expected another background refresh after the backoff, got %v", refreshed)
+	}
+	refreshBackoff = time.Hour
+
+	// or a refresh succeeds
+	if _, err := k.RefreshNamespaceList(ctx, "alpha-dev"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.GetNamespaceList(ctx, "alpha-dev"); err != nil {
+		t.Fatal(err)
+	}
+	if len(refreshed) != 3 {
+		t.Errorf("expected a background refresh after a successful one, got %v", refreshed)
+	}
+
+	// a context pointed at another cluster misses the cache
+	k.TTL = time.Hour
+	inner.server = "https://elsewhere.example.com"
+	lookups := inner.lookups
+	if _, err := k.GetNamespaceList(ctx, "alpha-dev"); err != nil {
+		t.Fatal(err)
+	}
+	if inner.lookups != lookups+1 {
+		t.Errorf("expected another server to miss the cache, got %d lookups", inner.lookups)
+	}
+
+	entries, err := k.Entries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Context != "alpha-dev" {
+		t.Errorf("expected two entries for alpha-dev, got %+v", entries)
+	}
+
+	if err := k.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := k.Entries(); len(entries) != 0 {
+		t.Errorf("expected no entries after clear, got %+v", entries)
+	}
+}
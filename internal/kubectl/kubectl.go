@@ -21,39 +21,146 @@
 package kubectl
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
 	"os/exec"
 	"strings"
+	"time"
+
+	"github.com/jesselang/kcn/internal/kubeconfig"
 )
 
 const (
 	DefaultNamespace = "default"
+
+	// BackendNative reads kubeconfig files directly
+	BackendNative = "native"
+	// BackendCommand shells out to kubectl for every lookup
+	BackendCommand = "command"
+
+	// how long a kubectl command may run, unless configured otherwise
+	DefaultTimeout = 5 * time.Second
 )
 
 type Kubectl interface {
-	GetContextList() ([]string, error)
-	GetCurrentContext() (string, error)
-	GetNamespaceList(context string) ([]string, error)
+	GetContextList(ctx context.Context) ([]string, error)
+	GetCurrentContext(ctx context.Context) (string, error)
+	GetContext(ctx context.Context, name string) (*Context, error)
+	GetNamespaceList(ctx context.Context, context string) ([]string, error)
 }
 
-type Command struct{}
+// details of a kubeconfig context
+type Context struct {
+	Name      string
+	Cluster   string
+	Server    string
+	User      string
+	Namespace string
+}
 
+// NewKubectl returns the default implementation, which reads kubeconfig
+// natively and only runs kubectl to talk to the API server.
 func NewKubectl() Kubectl {
-	return &Command{}
+	return NewNative()
+}
+
+// New returns the implementation for the named backend, or the default when
+// backend is empty. Each kubectl command it runs is stopped after timeout.
+func New(backend string, timeout time.Duration) (Kubectl, error) {
+	switch backend {
+	case "", BackendNative:
+		return &Native{command: Command{Timeout: timeout}}, nil
+	case BackendCommand:
+		return &Command{Timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %s", backend)
+	}
 }
 
-func (k *Command) GetContextList() ([]string, error) {
-	out, err := exec.Command("kubectl", "config", "get-contexts", "-o", "name").Output()
+type Command struct {
+	// stops each kubectl command, unless zero
+	Timeout time.Duration
+}
+
+func NewCommand() Kubectl {
+	return &Command{Timeout: DefaultTimeout}
+}
+
+func (k *Command) GetContextList(ctx context.Context) ([]string, error) {
+	out, err := k.output(ctx, "config", "get-contexts", "-o", "name")
 	return strings.Split(strings.TrimSpace(string(out)), "\n"), err
 }
 
-func (k *Command) GetCurrentContext() (string, error) {
-	out, err := exec.Command("kubectl", "config", "current-context").Output()
+func (k *Command) GetCurrentContext(ctx context.Context) (string, error) {
+	out, err := k.output(ctx, "config", "current-context")
 	return strings.TrimSpace(string(out)), err
 }
 
-func (k *Command) GetNamespaceList(context string) ([]string, error) {
-	out, err := exec.Command("kubectl", "--context", context,
+func (k *Command) GetContext(ctx context.Context, name string) (*Context, error) {
+	out, err := k.output(ctx, "config", "view", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var c kubeconfig.Config
+	if err := json.Unmarshal(out, &c); err != nil {
+		return nil, err
+	}
+
+	return contextFromConfig(&c, name)
+}
+
+func (k *Command) GetNamespaceList(ctx context.Context, context string) ([]string, error) {
+	out, err := k.output(ctx, "--context", context,
 		"get", "namespaces", "-o", "template",
-		"--template={{range .items}}{{.metadata.name}} {{end}}").Output()
+		"--template={{range .items}}{{.metadata.name}} {{end}}")
 	return strings.Split(strings.TrimSpace(string(out)), " "), err
 }
+
+// output runs kubectl against the user's kubeconfig, rather than the
+// per-session kubeconfig kcn layers on top of it, until it exits, k.Timeout
+// passes or ctx is done.
+func (k *Command) output(ctx context.Context, args ...string) ([]byte, error) {
+	if k.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, k.Timeout)
+		defer cancel()
+	}
+
+	cmd := exec.CommandContext(ctx, "kubectl", args...)
+	if orig := os.Getenv(kubeconfig.EnvOriginal); len(orig) > 0 {
+		cmd.Env = append(os.Environ(), kubeconfig.EnvKubeconfig+"="+orig)
+	}
+
+	out, err := cmd.Output()
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return nil, fmt.Errorf("kubectl timed out after %s", k.Timeout)
+	case context.Canceled:
+		return nil, ctx.Err()
+	}
+
+	return out, err
+}
+
+func contextFromConfig(c *kubeconfig.Config, name string) (*Context, error) {
+	ctx, ok := c.Context(name)
+	if !ok {
+		return nil, fmt.Errorf("context %s not found", name)
+	}
+
+	details := &Context{
+		Name:      name,
+		Cluster:   ctx.Cluster,
+		User:      ctx.User,
+		Namespace: ctx.Namespace,
+	}
+
+	if cluster, ok := c.Cluster(ctx.Cluster); ok {
+		details.Server = cluster.Server
+	}
+
+	return details, nil
+}
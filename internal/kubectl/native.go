@@ -0,0 +1,80 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package kubectl
+
+import (
+	"context"
+
+	"github.com/jesselang/kcn/internal/kubeconfig"
+)
+
+// Native answers config-only lookups by reading kubeconfig files directly,
+// and falls back to running kubectl for lookups against the API server.
+type Native struct {
+	// kubeconfig files to read, resolved from the environment when nil
+	Paths []string
+
+	command Command
+}
+
+func NewNative() Kubectl {
+	return &Native{command: Command{Timeout: DefaultTimeout}}
+}
+
+func (k *Native) load() (*kubeconfig.Config, error) {
+	paths := k.Paths
+	if paths == nil {
+		paths = kubeconfig.Paths()
+	}
+
+	return kubeconfig.Load(paths...)
+}
+
+func (k *Native) GetContextList(ctx context.Context) ([]string, error) {
+	c, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return c.ContextNames(), nil
+}
+
+func (k *Native) GetCurrentContext(ctx context.Context) (string, error) {
+	c, err := k.load()
+	if err != nil {
+		return "", err
+	}
+
+	return c.Current()
+}
+
+func (k *Native) GetContext(ctx context.Context, name string) (*Context, error) {
+	c, err := k.load()
+	if err != nil {
+		return nil, err
+	}
+
+	return contextFromConfig(c, name)
+}
+
+func (k *Native) GetNamespaceList(ctx context.Context, context string) ([]string, error) {
+	return k.command.GetNamespaceList(ctx, context)
+}
@@ -20,10 +20,17 @@
 
 package kubectl
 
+import (
+	"context"
+	"fmt"
+)
+
 type Mock struct {
 	contextList    []string
 	currentContext string
 	namespaceList  map[string][]string
+	// the namespace set on contexts in kubeconfig
+	contextNamespace map[string]string
 }
 
 func NewMock() Kubectl {
@@ -34,6 +41,9 @@ func NewMock() Kubectl {
 			"delta-prod",
 		},
 		currentContext: "bravo-stage",
+		contextNamespace: map[string]string{
+			"delta-prod": "app-y",
+		},
 		namespaceList: map[string][]string{
 			"alpha-dev": []string{
 				"app-a",
@@ -60,15 +70,31 @@ func NewMock() Kubectl {
 	}
 }
 
-func (k *Mock) GetContextList() ([]string, error) {
+func (k *Mock) GetContextList(ctx context.Context) ([]string, error) {
 	return k.contextList, nil
 }
 
-func (k *Mock) GetCurrentContext() (string, error) {
+func (k *Mock) GetCurrentContext(ctx context.Context) (string, error) {
 	return k.currentContext, nil
 }
 
-func (k *Mock) GetNamespaceList(context string) ([]string, error) {
+func (k *Mock) GetContext(ctx context.Context, name string) (*Context, error) {
+	for _, v := range k.contextList {
+		if v == name {
+			return &Context{
+				Name:      name,
+				Cluster:   name,
+				Server:    fmt.Sprintf("https://%s.example.com", name),
+				User:      name,
+				Namespace: k.contextNamespace[name],
+			}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("context %s not found", name)
+}
+
+func (k *Mock) GetNamespaceList(ctx context.Context, context string) ([]string, error) {
 	if v, ok := k.namespaceList[context]; ok {
 		return v, nil
 	} else {
@@ -0,0 +1,115 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package labels matches contexts by the labels configured for them, using
+// selectors like env=stage,region!=eu,team.
+package labels
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Requirement is one term of a selector.
+type Requirement struct {
+	Key   string
+	Value string
+	// Value must not match, or Key must be absent when Value is empty
+	Not bool
+	// only Key's presence is required
+	Exists bool
+}
+
+// Selector is a set of requirements, which must all be met.
+type Selector []Requirement
+
+// Parse parses a comma separated list of key=value, key!=value, key and !key
+// terms.
+func Parse(s string) (Selector, error) {
+	var sel Selector
+	for _, term := range strings.Split(s, ",") {
+		term = strings.TrimSpace(term)
+
+		var r Requirement
+		switch {
+		case len(term) == 0:
+			return nil, fmt.Errorf("invalid selector %q: empty term", s)
+		case strings.Contains(term, "!="):
+			parts := strings.SplitN(term, "!=", 2)
+			r = Requirement{Key: parts[0], Value: parts[1], Not: true}
+		case strings.Contains(term, "="):
+			parts := strings.SplitN(strings.Replace(term, "==", "=", 1), "=", 2)
+			r = Requirement{Key: parts[0], Value: parts[1]}
+		case strings.HasPrefix(term, "!"):
+			r = Requirement{Key: term[1:], Not: true, Exists: true}
+		default:
+			r = Requirement{Key: term, Exists: true}
+		}
+
+		r.Key = strings.TrimSpace(r.Key)
+		r.Value = strings.TrimSpace(r.Value)
+		if len(r.Key) == 0 {
+			return nil, fmt.Errorf("invalid selector %q: missing key in %s", s, term)
+		}
+
+		sel = append(sel, r)
+	}
+
+	return sel, nil
+}
+
+// Matches reports whether labels meet every requirement of sel.
+func (sel Selector) Matches(labels map[string]string) bool {
+	for _, r := range sel {
+		v, ok := labels[r.Key]
+
+		var met bool
+		switch {
+		case r.Exists:
+			met = ok
+		default:
+			met = ok && v == r.Value
+		}
+
+		if met == r.Not {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (sel Selector) String() string {
+	terms := make([]string, 0, len(sel))
+	for _, r := range sel {
+		switch {
+		case r.Exists && r.Not:
+			terms = append(terms, "!"+r.Key)
+		case r.Exists:
+			terms = append(terms, r.Key)
+		case r.Not:
+			terms = append(terms, r.Key+"!="+r.Value)
+		default:
+			terms = append(terms, r.Key+"="+r.Value)
+		}
+	}
+
+	return strings.Join(terms, ",")
+}
@@ -0,0 +1,94 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package labels
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		selector string
+		expected string
+		err      bool
+	}{
+		{"env=stage", "env=stage", false},
+		{"env==stage, region=eu", "env=stage,region=eu", false},
+		{"env!=prod,team,!legacy", "env!=prod,team,!legacy", false},
+		{"env=", "env=", false},
+		{"", "", true},
+		{"env=stage,", "", true},
+		{"=stage", "", true},
+		{"!", "", true},
+	}
+
+	for _, test := range tests {
+		sel, err := Parse(test.selector)
+		if test.err {
+			if err == nil {
+				t.Errorf("Parse(%q) = %s, expected error", test.selector, sel)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %s", test.selector, err)
+			continue
+		}
+		if sel.String() != test.expected {
+			t.Errorf("Parse(%q) = %s, expected %s", test.selector, sel, test.expected)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	labels := map[string]string{"env": "stage", "region": "eu", "team": "payments"}
+
+	tests := []struct {
+		selector string
+		expected bool
+	}{
+		{"env=stage", true},
+		{"env=stage,region=eu", true},
+		{"env=stage,region=us", false},
+		{"env!=prod", true},
+		{"env!=stage", false},
+		{"tier!=gold", true},
+		{"team", true},
+		{"tier", false},
+		{"!tier", true},
+		{"!team", false},
+	}
+
+	for _, test := range tests {
+		sel, err := Parse(test.selector)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %s", test.selector, err)
+		}
+		if actual := sel.Matches(labels); actual != test.expected {
+			t.Errorf("%s matches %v = %t, expected %t", test.selector, labels, actual, test.expected)
+		}
+	}
+
+	sel, _ := Parse("env=stage")
+	if sel.Matches(nil) {
+		t.Errorf("env=stage matches no labels")
+	}
+}
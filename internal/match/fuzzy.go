@@ -0,0 +1,110 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package match implements the fuzzy, prefix and pattern matching used to
+// select contexts and namespaces.
+package match
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Fuzzy reports whether the characters of pattern appear in s in order,
+// ignoring case, and scores the match. Higher scores are better matches:
+// consecutive characters, characters at the start of a word and a match at
+// the start of s all score higher.
+func Fuzzy(pattern, s string) (int, bool) {
+	if len(pattern) == 0 {
+		return 0, true
+	}
+
+	p := []rune(strings.ToLower(pattern))
+	r := []rune(s)
+	lower := []rune(strings.ToLower(s))
+
+	score := 0
+	pi := 0
+	last := -1
+	for i := 0; i < len(lower) && pi < len(p); i++ {
+		if lower[i] != p[pi] {
+			continue
+		}
+
+		score++
+		if i == 0 {
+			score += 8
+		} else if isBoundary(r[i-1], r[i]) {
+			score += 4
+		}
+		if last >= 0 && last == i-1 {
+			score += 5
+		}
+
+		last = i
+		pi++
+	}
+
+	if pi < len(p) {
+		return 0, false
+	}
+
+	// prefer shorter candidates when everything else is equal
+	return score*100 - len(r), true
+}
+
+// a word starts after a separator or at a lower to upper case change
+func isBoundary(prev, curr rune) bool {
+	if !unicode.IsLetter(prev) && !unicode.IsDigit(prev) {
+		return true
+	}
+
+	return unicode.IsLower(prev) && unicode.IsUpper(curr)
+}
+
+// Filter returns the items matching pattern, best match first. Items with
+// equal scores keep their relative order, so callers can rank by recency.
+func Filter(pattern string, items []string) []string {
+	type scored struct {
+		item  string
+		score int
+	}
+
+	var matches []scored
+	for _, v := range items {
+		if score, ok := Fuzzy(pattern, v); ok {
+			matches = append(matches, scored{v, score})
+		}
+	}
+
+	if len(pattern) > 0 {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].score > matches[j].score
+		})
+	}
+
+	filtered := make([]string, 0, len(matches))
+	for _, v := range matches {
+		filtered = append(filtered, v.item)
+	}
+
+	return filtered
+}
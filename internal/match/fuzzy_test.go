@@ -0,0 +1,77 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package match
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzy(t *testing.T) {
+	cases := []struct {
+		pattern string
+		s       string
+		ok      bool
+	}{
+		{"", "alpha-dev", true},
+		{"adev", "alpha-dev", true},
+		{"ADEV", "alpha-dev", true},
+		{"dev", "alpha-dev", true},
+		{"vde", "alpha-dev", false},
+		{"alpha-devs", "alpha-dev", false},
+	}
+
+	for _, c := range cases {
+		if _, ok := Fuzzy(c.pattern, c.s); ok != c.ok {
+			t.Errorf("Fuzzy(%q, %q) should be %t", c.pattern, c.s, c.ok)
+		}
+	}
+}
+
+func TestFuzzyScoring(t *testing.T) {
+	prefix, _ := Fuzzy("de", "delta-prod")
+	inner, _ := Fuzzy("de", "alpha-dev")
+	scattered, _ := Fuzzy("de", "bravo-stage")
+
+	if prefix <= inner {
+		t.Errorf("prefix match should outscore word match, %d <= %d",
+			prefix, inner)
+	}
+
+	if inner <= scattered {
+		t.Errorf("word match should outscore scattered match, %d <= %d",
+			inner, scattered)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	items := []string{"bravo-stage", "alpha-dev", "delta-prod"}
+
+	if got := Filter("", items); !reflect.DeepEqual(got, items) {
+		t.Errorf("empty pattern should keep order, got %v", got)
+	}
+
+	got := Filter("d", items)
+	expected := []string{"delta-prod", "alpha-dev"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
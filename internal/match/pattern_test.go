@@ -0,0 +1,58 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package match
+
+import (
+	"testing"
+)
+
+func TestPattern(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		ok      bool
+	}{
+		{"delta-prod", "delta-prod", true},
+		{"delta-prod", "delta-prod-2", false},
+		{"*-prod", "delta-prod", true},
+		{"*-prod", "gke_acme/delta-prod", true},
+		{"*prod*", "arn:aws:eks:us-east-1:123:cluster/prod", true},
+		{"d?lta-prod", "delta-prod", true},
+		{"[ab]*-dev", "alpha-dev", true},
+		{"[!ab]*-dev", "alpha-dev", false},
+		{`delta\*`, "delta*", true},
+		{`delta\*`, "delta-prod", false},
+		{"delta.prod", "delta-prod", false},
+		{"gke_*_prod", "gke_acme_us-east1_prod", true},
+		{"/prod$/", "gke_acme_us-east1_prod", true},
+		{"/prod$/", "prod-cluster", false},
+		{"/^(alpha|bravo)-/", "bravo-stage", true},
+		{"/[/", "[", false},
+		{"[", "[", false},
+		{"/", "/", true},
+	}
+
+	for _, c := range cases {
+		if ok := Pattern(c.pattern, c.name); ok != c.ok {
+			t.Errorf("Pattern(%q, %q) should be %t", c.pattern, c.name, c.ok)
+		}
+	}
+}
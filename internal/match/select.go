@@ -0,0 +1,62 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package match
+
+import (
+	"strings"
+)
+
+// Select returns the candidates name could refer to. An exact match wins,
+// followed by candidates starting with name, then candidates containing name,
+// then fuzzy matches; only the first of these tiers with any matches is
+// returned. When exact is set, only an exact match is considered.
+func Select(name string, candidates []string, exact bool) []string {
+	for _, v := range candidates {
+		if v == name {
+			return []string{v}
+		}
+	}
+
+	if exact || len(name) == 0 {
+		return nil
+	}
+
+	tiers := []func(string) bool{
+		func(v string) bool { return strings.HasPrefix(v, name) },
+		func(v string) bool { return strings.Contains(v, name) },
+		func(v string) bool { _, ok := Fuzzy(name, v); return ok },
+	}
+
+	for _, matches := range tiers {
+		var found []string
+		for _, v := range candidates {
+			if matches(v) {
+				found = append(found, v)
+			}
+		}
+
+		if len(found) > 0 {
+			return found
+		}
+	}
+
+	return nil
+}
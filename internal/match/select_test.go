@@ -0,0 +1,60 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package match
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	candidates := []string{
+		"gke_acme_us-east1_prod",
+		"gke_acme_us-east1_stage",
+		"prod",
+		"minikube",
+	}
+
+	cases := []struct {
+		name     string
+		exact    bool
+		expected []string
+	}{
+		{"prod", false, []string{"prod"}},
+		{"min", false, []string{"minikube"}},
+		{"gke", false, candidates[:2]},
+		{"east1_st", false, []string{"gke_acme_us-east1_stage"}},
+		{"_prod", false, []string{"gke_acme_us-east1_prod"}},
+		{"gap", false, []string{"gke_acme_us-east1_prod"}},
+		{"mkb", false, []string{"minikube"}},
+		{"min", true, nil},
+		{"nothing", false, nil},
+		{"", false, nil},
+	}
+
+	for _, c := range cases {
+		got := Select(c.name, candidates, c.exact)
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("Select(%q, exact=%t) expected %v, got %v",
+				c.name, c.exact, c.expected, got)
+		}
+	}
+}
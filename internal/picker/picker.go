@@ -0,0 +1,282 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package picker is a small fuzzy finder that runs on the controlling
+// terminal, so kcn doesn't depend on an external fzf binary.
+package picker
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/jesselang/kcn/internal/match"
+)
+
+const (
+	// number of items shown below the query line
+	height = 10
+
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyCtrlJ     = 10
+	keyCtrlK     = 11
+	keyCtrlN     = 14
+	keyCtrlP     = 16
+	keyCtrlU     = 21
+	keyEnter     = 13
+	keyEscape    = 27
+	keyBackspace = 127
+	keyCtrlH     = 8
+)
+
+var (
+	// ErrCanceled is returned when the user leaves the picker without
+	// choosing an item.
+	ErrCanceled = errors.New("canceled")
+	// ErrNoTerminal is returned when there is no terminal to pick on.
+	ErrNoTerminal = errors.New("not a terminal")
+)
+
+// Item is a choice offered by the picker.
+type Item struct {
+	Name string
+	// highlighted as the session's current selection
+	Current bool
+}
+
+// Pick lets the user fuzzy find one of items on the controlling terminal.
+// Items are offered in the order given, which should rank recently used
+// entries first.
+func Pick(prompt string, items []Item) (string, error) {
+	if !IsTerminal(os.Stdin) {
+		return "", ErrNoTerminal
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", ErrNoTerminal
+	}
+	defer tty.Close()
+
+	restore, err := rawMode(tty)
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	p := &picker{
+		out:    tty,
+		prompt: prompt,
+		items:  items,
+	}
+	p.filter()
+
+	defer p.clear()
+
+	buf := make([]byte, 16)
+	for {
+		p.render()
+
+		n, err := tty.Read(buf)
+		if err != nil {
+			return "", err
+		}
+
+		if done, err := p.handle(buf[:n]); done {
+			if err != nil {
+				return "", err
+			}
+			return p.matches[p.cursor].Name, nil
+		}
+	}
+}
+
+// List writes items to w, one per line, marking the current item. It is
+// used in place of Pick when there is no terminal.
+func List(w io.Writer, items []Item) {
+	for _, v := range items {
+		mark := " "
+		if v.Current {
+			mark = "*"
+		}
+		fmt.Fprintf(w, "%s %s\n", mark, v.Name)
+	}
+}
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	cmd := exec.Command("stty", "-g")
+	cmd.Stdin = f
+	return cmd.Run() == nil
+}
+
+// rawMode puts tty into raw mode, returning a function that restores it.
+func rawMode(tty *os.File) (func(), error) {
+	stty := func(args ...string) ([]byte, error) {
+		cmd := exec.Command("stty", args...)
+		cmd.Stdin = tty
+		return cmd.Output()
+	}
+
+	saved, err := stty("-g")
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		stty(strings.TrimSpace(string(saved)))
+	}, nil
+}
+
+type picker struct {
+	out    io.Writer
+	prompt string
+	items  []Item
+
+	query   []rune
+	matches []Item
+	cursor  int
+}
+
+func (p *picker) filter() {
+	names := make([]string, 0, len(p.items))
+	byName := map[string]Item{}
+	for _, v := range p.items {
+		names = append(names, v.Name)
+		byName[v.Name] = v
+	}
+
+	p.matches = p.matches[:0]
+	for _, v := range match.Filter(string(p.query), names) {
+		p.matches = append(p.matches, byName[v])
+	}
+
+	p.cursor = 0
+}
+
+// handle processes one read of input, returning true when the picker is done.
+func (p *picker) handle(input []byte) (bool, error) {
+	if len(input) > 1 && input[0] == keyEscape {
+		switch string(input[1:]) {
+		case "[A", "OA":
+			p.move(-1)
+		case "[B", "OB":
+			p.move(1)
+		}
+		return false, nil
+	}
+
+	for _, c := range string(input) {
+		switch c {
+		case keyCtrlC, keyCtrlD, keyEscape:
+			return true, ErrCanceled
+		case keyEnter, keyCtrlJ:
+			if len(p.matches) == 0 {
+				continue
+			}
+			return true, nil
+		case keyCtrlP, keyCtrlK:
+			p.move(-1)
+		case keyCtrlN:
+			p.move(1)
+		case keyBackspace, keyCtrlH:
+			if len(p.query) > 0 {
+				p.query = p.query[:len(p.query)-1]
+				p.filter()
+			}
+		case keyCtrlU:
+			p.query = nil
+			p.filter()
+		default:
+			if c >= ' ' {
+				p.query = append(p.query, c)
+				p.filter()
+			}
+		}
+	}
+
+	return false, nil
+}
+
+func (p *picker) move(delta int) {
+	p.cursor += delta
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+	if p.cursor >= len(p.matches) {
+		p.cursor = len(p.matches) - 1
+	}
+	if p.cursor < 0 {
+		p.cursor = 0
+	}
+}
+
+// render draws the query line and matches below the cursor, then returns the
+// cursor to the end of the query line. The terminal is in raw mode, so lines
+// are ended with \r\n.
+func (p *picker) render() {
+	var b strings.Builder
+
+	b.WriteString("\r\033[J")
+
+	start := 0
+	if p.cursor >= height {
+		start = p.cursor - height + 1
+	}
+
+	lines := 0
+	for i := start; i < len(p.matches) && i < start+height; i++ {
+		v := p.matches[i]
+
+		pointer := "  "
+		if i == p.cursor {
+			pointer = "> "
+		}
+
+		name := v.Name
+		if v.Current {
+			// bold, with a marker for terminals without bold
+			name = "\033[1m" + name + " *\033[0m"
+		}
+
+		b.WriteString("\r\n" + pointer + name)
+		lines++
+	}
+
+	b.WriteString(fmt.Sprintf("\r\n  %d/%d", len(p.matches), len(p.items)))
+	lines++
+
+	b.WriteString(fmt.Sprintf("\033[%dA\r%s> %s", lines, p.prompt, string(p.query)))
+
+	io.WriteString(p.out, b.String())
+}
+
+// clear erases everything drawn by render.
+func (p *picker) clear() {
+	io.WriteString(p.out, "\r\033[J")
+}
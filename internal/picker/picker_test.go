@@ -0,0 +1,66 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package picker
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestPickerHandle(t *testing.T) {
+	p := &picker{
+		out: ioutil.Discard,
+		items: []Item{
+			{Name: "bravo-stage", Current: true},
+			{Name: "alpha-dev"},
+			{Name: "delta-prod"},
+		},
+	}
+	p.filter()
+
+	if done, _ := p.handle([]byte("\033[B")); done {
+		t.Fatal("arrow key should not finish picking")
+	}
+	if p.matches[p.cursor].Name != "alpha-dev" {
+		t.Errorf("down should select second item, got %s",
+			p.matches[p.cursor].Name)
+	}
+
+	p.handle([]byte("dp"))
+	if len(p.matches) != 1 || p.cursor != 0 {
+		t.Fatalf("query should narrow matches, got %+v", p.matches)
+	}
+
+	p.render()
+
+	done, err := p.handle([]byte{keyEnter})
+	if !done || err != nil {
+		t.Fatalf("enter should pick, got %t %v", done, err)
+	}
+	if p.matches[p.cursor].Name != "delta-prod" {
+		t.Errorf("expected delta-prod, got %s", p.matches[p.cursor].Name)
+	}
+
+	done, err = p.handle([]byte{keyEscape})
+	if !done || err != ErrCanceled {
+		t.Errorf("escape should cancel, got %t %v", done, err)
+	}
+}
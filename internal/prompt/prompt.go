@@ -0,0 +1,199 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package prompt renders the session's selection for shell prompts.
+package prompt
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// DefaultFormat is used when no format is configured.
+const DefaultFormat = `{{.Color}}{{.Context}}/{{.Namespace}}{{.Reset}}`
+
+// Data is what a format can refer to.
+type Data struct {
+	Context   string
+	Namespace string
+	// short name of the context's cluster, see ShortName
+	Cluster string
+	// the alias the selection was made by, if any
+	Alias     string
+	Protected bool
+	// number of selections in the session's stack
+	Depth int
+	// configured for the context, like {{.Labels.env}}
+	Labels map[string]string
+
+	// escape sequences that start and end the context's color, empty when
+	// it has none
+	Color string
+	Reset string
+}
+
+// Render executes format with data.
+func Render(format string, data Data) (string, error) {
+	t, err := template.New("prompt").Parse(format)
+	if err != nil {
+		return "", err
+	}
+
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+
+	return b.String(), nil
+}
+
+// ShortName shortens the context names generated by cloud CLIs to the
+// cluster's name, like gke_project_zone_name, EKS ARNs ending in
+// cluster/name and AKS or kops names with a domain. Other names are returned
+// as is.
+func ShortName(context string) string {
+	if strings.HasPrefix(context, "gke_") {
+		parts := strings.Split(context, "_")
+		return parts[len(parts)-1]
+	}
+
+	if strings.HasPrefix(context, "arn:") {
+		if i := strings.LastIndex(context, "/"); i >= 0 {
+			return context[i+1:]
+		}
+	}
+
+	if i := strings.Index(context, "@"); i >= 0 {
+		// user@cluster, as written by eksctl
+		context = context[i+1:]
+	}
+
+	if i := strings.Index(context, "."); i > 0 {
+		return context[:i]
+	}
+
+	return context
+}
+
+var colors = map[string]int{
+	"black":   0,
+	"red":     1,
+	"green":   2,
+	"yellow":  3,
+	"blue":    4,
+	"magenta": 5,
+	"cyan":    6,
+	"white":   7,
+}
+
+// Color returns the escape sequences that start and end color, which is one
+// of the eight basic color names, optionally prefixed by bright- or bold-, or
+// a 256-color number.
+func Color(color string) (string, string, error) {
+	if len(color) == 0 {
+		return "", "", nil
+	}
+
+	var attrs []string
+	name := color
+	if strings.HasPrefix(name, "bold-") {
+		attrs = append(attrs, "1")
+		name = strings.TrimPrefix(name, "bold-")
+	}
+
+	if n, ok := colors[strings.TrimPrefix(name, "bright-")]; ok {
+		if strings.HasPrefix(name, "bright-") {
+			n += 60
+		}
+		attrs = append(attrs, strconv.Itoa(30+n))
+	} else if n, err := strconv.Atoi(name); err == nil && n >= 0 && n < 256 {
+		attrs = append(attrs, "38;5;"+name)
+	} else {
+		return "", "", fmt.Errorf("unknown color %s", color)
+	}
+
+	return "\x1b[" + strings.Join(attrs, ";") + "m", "\x1b[0m", nil
+}
+
+// Escape wraps the escape sequence seq so that shell doesn't count it
+// towards the prompt's width.
+func Escape(shell, seq string) string {
+	if len(seq) == 0 {
+		return seq
+	}
+
+	switch shell {
+	case "zsh":
+		return "%{" + seq + "%}"
+	case "bash":
+		// \[ and \] aren't interpreted in the output of commands in PS1
+		return "\x01" + seq + "\x02"
+	default:
+		return seq
+	}
+}
+
+// integrations are snippets for prompt frameworks, which do their own
+// coloring
+var integrations = map[string]string{
+	"starship": `# add to ~/.config/starship.toml
+[custom.kcn]
+command = "kcn prompt --no-color"
+when = """ test -n "$KCN_CONTEXT" """
+symbol = "☸ "
+style = "cyan"
+format = "[$symbol$output]($style) "
+shell = ["sh", "--norc"]
+`,
+	"p10k": `# add to ~/.p10k.zsh, and kcn to POWERLEVEL9K_LEFT_PROMPT_ELEMENTS
+function prompt_kcn() {
+  [[ -n $KCN_CONTEXT ]] || return
+  local color=cyan
+  [[ -n $KCN_PROTECTED ]] && color=red
+  p10k segment -f $color -i '☸' -t "$(command kcn prompt --no-color)"
+}
+`,
+}
+
+// Integrations returns the names of the prompt frameworks with snippets.
+func Integrations() []string {
+	names := make([]string, 0, len(integrations))
+	for k := range integrations {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Integration returns the snippet for the named prompt framework.
+func Integration(name string) (string, error) {
+	snippet, ok := integrations[name]
+	if !ok {
+		return "", fmt.Errorf("unknown prompt %s, expected one of: %s",
+			name, strings.Join(Integrations(), ", "))
+	}
+
+	return snippet, nil
+}
@@ -0,0 +1,99 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package prompt
+
+import (
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	data := Data{
+		Context:   "gke_acme_us-east1_prod",
+		Namespace: "payments",
+		Cluster:   "prod",
+		Protected: true,
+		Depth:     3,
+		Labels:    map[string]string{"env": "prod"},
+		Color:     "<",
+		Reset:     ">",
+	}
+
+	cases := map[string]string{
+		DefaultFormat: "<gke_acme_us-east1_prod/payments>",
+		`{{.Cluster}}:{{.Namespace}}{{if .Protected}}!{{end}} [{{.Depth}}]`: "prod:payments! [3]",
+		`{{.Labels.env}}{{with .Labels.region}}-{{.}}{{end}}`:               "prod",
+		`{{or .Alias .Cluster}}`:                                            "prod",
+	}
+
+	for format, expected := range cases {
+		out, err := Render(format, data)
+		if err != nil {
+			t.Errorf("%s: %s", format, err)
+		} else if out != expected {
+			t.Errorf("%s: expected %q, got %q", format, expected, out)
+		}
+	}
+
+	if _, err := Render("{{.Nope}}", data); err == nil {
+		t.Error("unknown fields should fail")
+	}
+}
+
+func TestShortName(t *testing.T) {
+	cases := map[string]string{
+		"gke_acme_us-east1_prod":                           "prod",
+		"arn:aws:eks:us-east-1:123456789012:cluster/stage": "stage",
+		"admin@dev.us-east-1.eksctl.io":                    "dev",
+		"prod.k8s.example.com":                             "prod",
+		"alpha-dev":                                        "alpha-dev",
+	}
+
+	for context, expected := range cases {
+		if short := ShortName(context); short != expected {
+			t.Errorf("%s: expected %s, got %s", context, expected, short)
+		}
+	}
+}
+
+func TestColor(t *testing.T) {
+	cases := map[string]string{
+		"red":        "\x1b[31m",
+		"bright-red": "\x1b[91m",
+		"bold-cyan":  "\x1b[1;36m",
+		"208":        "\x1b[38;5;208m",
+		"":           "",
+	}
+
+	for color, expected := range cases {
+		start, _, err := Color(color)
+		if err != nil {
+			t.Errorf("%s: %s", color, err)
+		} else if start != expected {
+			t.Errorf("%s: expected %q, got %q", color, expected, start)
+		}
+	}
+
+	for _, v := range []string{"purple", "256"} {
+		if _, _, err := Color(v); err == nil {
+			t.Errorf("%s should be an unknown color", v)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package recent remembers the namespaces used with each context across
+// sessions, so they can be selected and ranked first.
+package recent
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/jesselang/kcn/internal/fsutil"
+)
+
+// Store is the file recording recent use, shared by all sessions.
+type Store struct {
+	Path string
+}
+
+// Recent is the recorded use of each context.
+type Recent struct {
+	Contexts map[string]*Context `json:"contexts"`
+}
+
+// Context is the recorded use of one context.
+type Context struct {
+	// the namespace last used
+	Namespace string `json:"namespace"`
+	// how many times the context was selected
+	Count int `json:"count"`
+	// when the context was last selected, in unix seconds
+	Time int64 `json:"time"`
+	// how many times each namespace was selected
+	Namespaces map[string]int `json:"namespaces"`
+}
+
+func NewStore(path string) *Store {
+	return &Store{Path: path}
+}
+
+// DefaultPath returns where recent use is recorded by default.
+func DefaultPath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "kcn", "recent.json"), nil
+}
+
+// Load reads the recorded use, which is empty when nothing was recorded yet.
+func (s *Store) Load() (*Recent, error) {
+	r := &Recent{Contexts: map[string]*Context{}}
+
+	b, err := ioutil.ReadFile(s.Path)
+	if os.IsNotExist(err) {
+		return r, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	// a corrupt file is started over, as it's only a convenience
+	if err := json.Unmarshal(b, r); err != nil || r.Contexts == nil {
+		r.Contexts = map[string]*Context{}
+	}
+
+	return r, nil
+}
+
+// Record notes that namespace was selected in context. Sessions recording
+// at the same time are serialized by a lock on the file.
+func (s *Store) Record(context, namespace string) error {
+	if len(context) == 0 {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		return err
+	}
+
+	unlock, err := fsutil.Lock(s.Path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	r, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	c, ok := r.Contexts[context]
+	if !ok {
+		c = &Context{}
+		r.Contexts[context] = c
+	}
+	if c.Namespaces == nil {
+		c.Namespaces = map[string]int{}
+	}
+
+	c.Count++
+	c.Time = time.Now().Unix()
+	if len(namespace) > 0 {
+		c.Namespace = namespace
+		c.Namespaces[namespace]++
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	return fsutil.WriteFile(s.Path, b, 0600)
+}
+
+// Namespace returns the namespace last used in context, if any.
+func (r *Recent) Namespace(context string) string {
+	if c, ok := r.Contexts[context]; ok {
+		return c.Namespace
+	}
+
+	return ""
+}
+
+// ContextNames returns the recorded contexts, most used first.
+func (r *Recent) ContextNames() []string {
+	counts := map[string]int{}
+	for k, v := range r.Contexts {
+		counts[k] = v.Count
+	}
+
+	return byCount(counts)
+}
+
+// NamespaceNames returns the namespaces recorded for context, most used
+// first.
+func (r *Recent) NamespaceNames(context string) []string {
+	c, ok := r.Contexts[context]
+	if !ok {
+		return nil
+	}
+
+	return byCount(c.Namespaces)
+}
+
+// byCount returns the keys of counts, highest count first and by name
+// otherwise.
+func byCount(counts map[string]int) []string {
+	names := make([]string, 0, len(counts))
+	for k := range counts {
+		names = append(names, k)
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	return names
+}
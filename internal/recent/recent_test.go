@@ -0,0 +1,128 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package recent
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func storeFixture(t *testing.T) *Store {
+	dir, err := ioutil.TempDir("", "kcn-recent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return NewStore(filepath.Join(dir, "kcn", "recent.json"))
+}
+
+func TestRecord(t *testing.T) {
+	s := storeFixture(t)
+
+	r, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(r.Namespace("alpha-dev")) > 0 || len(r.ContextNames()) > 0 {
+		t.Errorf("expected nothing recorded, got %+v", r.Contexts)
+	}
+
+	for _, v := range [][]string{
+		{"alpha-dev", "app-a"},
+		{"bravo-stage", "app-d"},
+		{"alpha-dev", "app-b"},
+		{"alpha-dev", "app-a"},
+		{"alpha-dev", "app-b"},
+		{"alpha-dev", "app-c"},
+	} {
+		if err := s.Record(v[0], v[1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	r, err = s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ns := r.Namespace("alpha-dev"); ns != "app-c" {
+		t.Errorf("expected the last namespace app-c, got %s", ns)
+	}
+	if names := r.ContextNames(); !reflect.DeepEqual(names, []string{"alpha-dev", "bravo-stage"}) {
+		t.Errorf("expected contexts by use, got %v", names)
+	}
+	expected := []string{"app-a", "app-b", "app-c"}
+	if names := r.NamespaceNames("alpha-dev"); !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestRecordConcurrent(t *testing.T) {
+	s := storeFixture(t)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// a store per writer, as each shell has its own
+			if err := NewStore(s.Path).Record("alpha-dev", "app-a"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	r, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := r.Contexts["alpha-dev"]; c == nil || c.Count != 20 {
+		t.Errorf("expected 20 records, got %+v", c)
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	s := storeFixture(t)
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(s.Path, []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Record("alpha-dev", "app-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ns := r.Namespace("alpha-dev"); ns != "app-a" {
+		t.Errorf("expected a corrupt file to be started over, got %+v", r.Contexts)
+	}
+}
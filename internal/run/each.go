@@ -0,0 +1,163 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package run
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// Options controls how Each runs a command.
+type Options struct {
+	// how many targets run at once, all of them when less than 1
+	Parallel int
+	// limits each target's run, when set
+	Timeout time.Duration
+	// skips the targets not yet started once one fails
+	FailFast bool
+	// writes each target's output at once under a header when it's done,
+	// rather than prefixing every line with its name
+	Group bool
+}
+
+// Result is the outcome of running a command for one target.
+type Result struct {
+	Target   Target
+	Code     int
+	Err      error
+	Duration time.Duration
+	// not run, because an earlier target failed with Options.FailFast
+	Skipped bool
+}
+
+// Failed reports whether the command failed or couldn't be run.
+func (r Result) Failed() bool {
+	return !r.Skipped && (r.Code != 0 || r.Err != nil)
+}
+
+// Each runs argv for every target, returning their results in the order of
+// targets. A failure for one target doesn't stop the others unless
+// opts.FailFast is set, which lets those already running finish, or ctx is
+// done, which stops them too. Commands get no stdin, since they run
+// concurrently.
+func Each(ctx context.Context, targets []Target, argv []string, opts Options, stdout, stderr io.Writer) []Result {
+	parallel := opts.Parallel
+	if parallel < 1 || parallel > len(targets) {
+		parallel = len(targets)
+	}
+
+	// done once no more targets should start; those running carry on
+	// unless ctx is done
+	start, stop := context.WithCancel(ctx)
+	defer stop()
+
+	results := make([]Result, len(targets))
+	indexes := make(chan int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if start.Err() != nil {
+					results[i] = Result{Target: targets[i], Skipped: true}
+					continue
+				}
+				results[i] = each(ctx, targets[i], argv, opts, stdout, stderr, &mu)
+				if opts.FailFast && results[i].Failed() {
+					stop()
+				}
+			}
+		}()
+	}
+
+	for i := range targets {
+		if start.Err() != nil {
+			results[i] = Result{Target: targets[i], Skipped: true}
+			continue
+		}
+
+		select {
+		case indexes <- i:
+		case <-start.Done():
+			results[i] = Result{Target: targets[i], Skipped: true}
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	return results
+}
+
+// each runs argv for target, writing its output under mu.
+func each(ctx context.Context, target Target, argv []string, opts Options,
+	stdout, stderr io.Writer, mu *sync.Mutex) Result {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	var out, errOut io.WriteCloser
+	var grouped bytes.Buffer
+	if opts.Group {
+		out = nopCloser{&grouped}
+		errOut = out
+	} else {
+		out = NewPrefixWriter(stdout, target.Name+": ", mu)
+		errOut = NewPrefixWriter(stderr, target.Name+": ", mu)
+	}
+
+	start := time.Now()
+	code, err := command(ctx, target, argv, nil, out, errOut)
+	result := Result{
+		Target:   target,
+		Code:     code,
+		Err:      err,
+		Duration: time.Since(start),
+	}
+
+	out.Close()
+	errOut.Close()
+
+	if opts.Group {
+		mu.Lock()
+		fmt.Fprintf(stdout, "==> %s <==\n", target.Name)
+		grouped.WriteTo(stdout)
+		mu.Unlock()
+	}
+
+	return result
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package run
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEach(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	var targets []Target
+	for _, v := range []string{"alpha-dev", "bravo-stage", "delta-prod"} {
+		targets = append(targets, Target{Name: v, Env: []string{"KCN_CONTEXT=" + v}})
+	}
+	argv := []string{"sh", "-c", `echo $KCN_CONTEXT; [ $KCN_CONTEXT != bravo-stage ]`}
+
+	var out bytes.Buffer
+	results := Each(ctx, targets, argv, Options{Parallel: 2}, &out, &out)
+
+	for i, v := range results {
+		if v.Target.Name != targets[i].Name {
+			t.Errorf("expected results in order of targets, got %s at %d", v.Target.Name, i)
+		}
+		if v.Failed() != (v.Target.Name == "bravo-stage") {
+			t.Errorf("unexpected result for %s: %+v", v.Target.Name, v)
+		}
+		if !strings.Contains(out.String(), v.Target.Name+": "+v.Target.Name+"\n") {
+			t.Errorf("expected prefixed output for %s, got %q", v.Target.Name, out.String())
+		}
+	}
+
+	out.Reset()
+	results = Each(ctx, targets, argv, Options{Parallel: 1, FailFast: true, Group: true}, &out, &out)
+	if !results[2].Skipped {
+		t.Errorf("expected delta-prod to be skipped, got %+v", results[2])
+	}
+	expected := "==> alpha-dev <==\nalpha-dev\n==> bravo-stage <==\nbravo-stage\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+
+	// a failure lets the targets already running finish
+	slow := []string{"sh", "-c", `[ $KCN_CONTEXT != alpha-dev ] && sleep 0.2`}
+	results = Each(ctx, targets, slow, Options{Parallel: 2, FailFast: true}, &out, &out)
+	if !results[0].Failed() || results[1].Failed() || results[1].Skipped || !results[2].Skipped {
+		t.Errorf("expected bravo-stage to finish and delta-prod to be skipped, got %+v", results)
+	}
+
+	results = Each(ctx, targets[:1], []string{"sleep", "5"}, Options{Timeout: 50 * time.Millisecond}, &out, &out)
+	if results[0].Code != 124 || results[0].Err == nil {
+		t.Errorf("expected a timeout, got %+v", results[0])
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	results = Each(canceled, targets, argv, Options{}, &out, &out)
+	for _, v := range results {
+		if !v.Skipped {
+			t.Errorf("expected every target to be skipped once canceled, got %+v", v)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package run runs commands against a selected context and namespace.
+package run
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"syscall"
+)
+
+// Target is the environment a command runs in.
+type Target struct {
+	// shown before each line of output when prefixing
+	Name string
+	// added to the environment, as KEY=value
+	Env []string
+}
+
+// Command runs argv in target's environment until it exits or ctx is done,
+// copying its output to stdout and stderr. The returned code is the command's
+// exit code, or 127 when it couldn't be started.
+func Command(ctx context.Context, target Target, argv []string, stdout, stderr io.Writer) (int, error) {
+	return command(ctx, target, argv, os.Stdin, stdout, stderr)
+}
+
+// command runs argv until it exits or ctx is done, returning 124 when ctx's
+// deadline passed, as timeout(1) does.
+func command(ctx context.Context, target Target, argv []string,
+	stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	c := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	c.Env = append(os.Environ(), target.Env...)
+	c.Stdin = stdin
+	c.Stdout = stdout
+	c.Stderr = stderr
+
+	err := c.Run()
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return 124, errors.New("timed out")
+	case context.Canceled:
+		return 130, errors.New("canceled")
+	}
+
+	return ExitCode(err)
+}
+
+// ExitCode returns the exit code for the error from running a command, and the
+// error itself when the command couldn't be run.
+func ExitCode(err error) (int, error) {
+	if err == nil {
+		return 0, nil
+	}
+
+	if exit, ok := err.(*exec.ExitError); ok {
+		if code := exit.ExitCode(); code >= 0 {
+			return code, nil
+		}
+		// killed by a signal, reported as shells do
+		if status, ok := exit.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return 128 + int(status.Signal()), nil
+		}
+		return 128 + int(syscall.SIGTERM), nil
+	}
+
+	return 127, err
+}
+
+// prefixWriter writes each complete line to w after prefix.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	w      io.Writer
+	prefix []byte
+	buf    []byte
+}
+
+// NewPrefixWriter returns a writer that copies lines to w, each preceded by
+// prefix. Writers sharing mu can write to the same w concurrently without
+// interleaving lines. Close writes any incomplete last line.
+func NewPrefixWriter(w io.Writer, prefix string, mu *sync.Mutex) io.WriteCloser {
+	if mu == nil {
+		mu = &sync.Mutex{}
+	}
+	return &prefixWriter{mu: mu, w: w, prefix: []byte(prefix)}
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.buf = append(p.buf, b...)
+
+	for {
+		i := bytes.IndexByte(p.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := p.line(p.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		p.buf = p.buf[i+1:]
+	}
+
+	return len(b), nil
+}
+
+func (p *prefixWriter) Close() error {
+	if len(p.buf) == 0 {
+		return nil
+	}
+
+	err := p.line(append(p.buf, '\n'))
+	p.buf = nil
+	return err
+}
+
+func (p *prefixWriter) line(l []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err := p.w.Write(append(append([]byte{}, p.prefix...), l...))
+	return err
+}
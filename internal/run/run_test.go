@@ -0,0 +1,104 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package run
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+var ctx = context.Background()
+
+func TestPrefixWriter(t *testing.T) {
+	var b bytes.Buffer
+	w := NewPrefixWriter(&b, "alpha-dev: ", nil)
+
+	for _, v := range []string{"one\ntw", "o\n", "three"} {
+		if _, err := w.Write([]byte(v)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if b.String() != "alpha-dev: one\nalpha-dev: two\n" {
+		t.Errorf("incomplete lines should be held back, got %q", b.String())
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "alpha-dev: one\nalpha-dev: two\nalpha-dev: three\n"
+	if b.String() != expected {
+		t.Errorf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	var out bytes.Buffer
+	code, err := Command(ctx, Target{Env: []string{"KCN_TEST=value"}},
+		[]string{"sh", "-c", "echo $KCN_TEST; exit 3"}, &out, &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != 3 {
+		t.Errorf("expected exit code 3, got %d", code)
+	}
+	if strings.TrimSpace(out.String()) != "value" {
+		t.Errorf("expected the target's environment, got %q", out.String())
+	}
+
+	code, err = Command(ctx, Target{}, []string{"kcn-no-such-command"}, &out, &out)
+	if err == nil || code != 127 {
+		t.Errorf("expected 127 and an error, got %d, %v", code, err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	if code, err := ExitCode(errors.New("boom")); code != 127 || err == nil {
+		t.Errorf("expected 127 and the error, got %d, %v", code, err)
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	tests := map[string]int{
+		"exit 7":        7,
+		"kill -INT $$":  130,
+		"kill -KILL $$": 137,
+		"kill -TERM $$": 143,
+	}
+	for script, expected := range tests {
+		code, err := ExitCode(exec.Command("sh", "-c", script).Run())
+		if code != expected || err != nil {
+			t.Errorf("%s: expected %d, got %d, %v", script, expected, code, err)
+		}
+	}
+}
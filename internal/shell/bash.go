@@ -0,0 +1,92 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shell
+
+import (
+	"fmt"
+)
+
+type bash struct{}
+
+func init() {
+	register(bash{})
+}
+
+func (bash) Name() string {
+	return "bash"
+}
+
+func (bash) Export(name, value string) string {
+	return fmt.Sprintf("export %s=%s\n", name, posixQuote(value))
+}
+
+func (bash) Unset(name string) string {
+	return fmt.Sprintf("unset %s\n", name)
+}
+
+func (b bash) Env(vars []Var) string {
+	return statements(b, vars)
+}
+
+func (b bash) Wrapper() string {
+	return bashWrapper(b.Name())
+}
+
+func (bash) Kubectl() string {
+	return bashKubectl
+}
+
+func (bash) Hook(args string) string {
+	return fmt.Sprintf(`_kcn_hook() {
+	if [[ $PWD != "${_kcn_pwd-}" ]]; then
+		_kcn_pwd=$PWD
+		kcn %s
+	fi
+};
+if [[ ";${PROMPT_COMMAND:-};" != *";_kcn_hook;"* ]]; then
+	PROMPT_COMMAND="_kcn_hook${PROMPT_COMMAND:+;$PROMPT_COMMAND}"
+fi
+`, args)
+}
+
+// the wrapper functions are shared with zsh
+
+func bashWrapper(name string) string {
+	return fmt.Sprintf(`kcn() {
+	command kcn "$@"
+	kcn_code=$?
+	source <(command kcn env --shell %s)
+	[[ $kcn_code -eq 0 ]] || return $kcn_code
+};
+`, name)
+}
+
+// earlier versions defined kubectl as an alias, which would be expanded in
+// the function definition
+const bashKubectl = `unalias kubectl 2>/dev/null
+kubectl() {
+	if [[ -n $KCN_PROTECTED ]]; then
+		command kcn guard -- "$@" || return $?
+	fi
+	command kubectl ${KCN_CONTEXT:+--context=$KCN_CONTEXT} \
+		${KCN_NAMESPACE:+--namespace=$KCN_NAMESPACE} "$@"
+};
+`
@@ -0,0 +1,87 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+type elvish struct{}
+
+func init() {
+	register(elvish{})
+}
+
+func (elvish) Name() string {
+	return "elvish"
+}
+
+func (elvish) Export(name, value string) string {
+	return fmt.Sprintf("set-env %s %s\n", name, elvishQuote(value))
+}
+
+func (elvish) Unset(name string) string {
+	return fmt.Sprintf("unset-env %s\n", name)
+}
+
+func (e elvish) Env(vars []Var) string {
+	return statements(e, vars)
+}
+
+func (elvish) Wrapper() string {
+	return `fn kcn {|@args|
+	var kcn_err = ?(e:kcn $@args)
+	eval (e:kcn env --shell elvish | slurp)
+	if (not $kcn_err) {
+		fail $kcn_err
+	}
+}
+`
+}
+
+func (elvish) Kubectl() string {
+	return `fn kubectl {|@args|
+	if (!=s $E:KCN_PROTECTED '') {
+		e:kcn guard -- $@args
+	}
+	var kcn_flags = []
+	if (!=s $E:KCN_CONTEXT '') {
+		set kcn_flags = [$@kcn_flags --context=$E:KCN_CONTEXT]
+	}
+	if (!=s $E:KCN_NAMESPACE '') {
+		set kcn_flags = [$@kcn_flags --namespace=$E:KCN_NAMESPACE]
+	}
+	e:kubectl $@kcn_flags $@args
+}
+`
+}
+
+func (elvish) Hook(args string) string {
+	return fmt.Sprintf(`set after-chdir = [$@after-chdir {|_| kcn %s }]
+kcn %s
+`, args, args)
+}
+
+// elvishQuote single quotes s, where a quote is escaped by doubling it
+func elvishQuote(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
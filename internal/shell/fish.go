@@ -0,0 +1,87 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+type fish struct{}
+
+func init() {
+	register(fish{})
+}
+
+func (fish) Name() string {
+	return "fish"
+}
+
+func (fish) Export(name, value string) string {
+	return fmt.Sprintf("set -gx %s %s\n", name, fishQuote(value))
+}
+
+func (fish) Unset(name string) string {
+	return fmt.Sprintf("set -e %s\n", name)
+}
+
+func (f fish) Env(vars []Var) string {
+	return statements(f, vars)
+}
+
+func (fish) Wrapper() string {
+	return `function kcn --wraps kcn
+	command kcn $argv
+	set -l kcn_code $status
+	command kcn env --shell fish | source
+	return $kcn_code
+end
+`
+}
+
+func (fish) Kubectl() string {
+	return `function kubectl --wraps kubectl
+	if test -n "$KCN_PROTECTED"
+		command kcn guard -- $argv; or return $status
+	end
+	set -l kcn_flags
+	test -n "$KCN_CONTEXT"; and set -a kcn_flags --context=$KCN_CONTEXT
+	test -n "$KCN_NAMESPACE"; and set -a kcn_flags --namespace=$KCN_NAMESPACE
+	command kubectl $kcn_flags $argv
+end
+`
+}
+
+func (fish) Hook(args string) string {
+	return fmt.Sprintf(`function __kcn_hook --on-variable PWD
+	kcn %s
+end
+__kcn_hook
+`, args)
+}
+
+// fishQuote single quotes s for fish, which only treats \\ and \' specially
+// within single quotes.
+func fishQuote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `'`, `\'`, -1)
+	return "'" + s + "'"
+}
@@ -0,0 +1,100 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shell
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// nushell can't source generated code at runtime, so kcn env prints a record
+// that the wrapper loads with load-env, and kcn env --init is meant to be
+// saved from env.nu and sourced from config.nu.
+type nushell struct{}
+
+func init() {
+	register(nushell{}, "nushell")
+}
+
+func (nushell) Name() string {
+	return "nu"
+}
+
+func (nushell) Export(name, value string) string {
+	return fmt.Sprintf("$env.%s = %s\n", name, nuQuote(value))
+}
+
+func (nushell) Unset(name string) string {
+	return fmt.Sprintf("hide-env -i %s\n", name)
+}
+
+// load-env can't unset variables, so they are set empty instead
+func (nushell) Env(vars []Var) string {
+	fields := make([]string, 0, len(vars))
+	for _, v := range vars {
+		fields = append(fields, fmt.Sprintf("%s: %s", v.Name, nuQuote(v.Value)))
+	}
+
+	return "{" + strings.Join(fields, ", ") + "}\n"
+}
+
+func (nushell) Wrapper() string {
+	return `def --env --wrapped kcn [...args] {
+	do --ignore-errors { ^kcn ...$args }
+	let kcn_code = $env.LAST_EXIT_CODE
+	^kcn env --shell nu | from nuon | load-env
+	if $kcn_code != 0 {
+		error make --unspanned { msg: $"kcn exited with code ($kcn_code)" }
+	}
+}
+`
+}
+
+func (nushell) Kubectl() string {
+	return `def --wrapped kubectl [...args] {
+	if ($env.KCN_PROTECTED? | default "" | is-not-empty) {
+		^kcn guard -- ...$args
+	}
+	mut kcn_flags = []
+	if ($env.KCN_CONTEXT? | default "" | is-not-empty) {
+		$kcn_flags = ($kcn_flags | append $"--context=($env.KCN_CONTEXT)")
+	}
+	if ($env.KCN_NAMESPACE? | default "" | is-not-empty) {
+		$kcn_flags = ($kcn_flags | append $"--namespace=($env.KCN_NAMESPACE)")
+	}
+	^kubectl ...$kcn_flags ...$args
+}
+`
+}
+
+func (nushell) Hook(args string) string {
+	return fmt.Sprintf(`$env.config.hooks.env_change.PWD = (
+	$env.config.hooks.env_change.PWD? | default [] | append {|before, after| kcn %s }
+)
+`, args)
+}
+
+// nuQuote double quotes s, which nushell escapes like JSON
+func nuQuote(s string) string {
+	b, _ := json.Marshal(s)
+	return string(b)
+}
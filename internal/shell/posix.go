@@ -0,0 +1,75 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shell
+
+import (
+	"fmt"
+)
+
+// posix is plain sh, without process substitution, [[ ]] or prompt hooks
+type posix struct{}
+
+func init() {
+	register(posix{}, "dash", "ash", "ksh")
+}
+
+func (posix) Name() string {
+	return "sh"
+}
+
+func (posix) Export(name, value string) string {
+	return fmt.Sprintf("export %s=%s\n", name, posixQuote(value))
+}
+
+func (posix) Unset(name string) string {
+	return fmt.Sprintf("unset %s\n", name)
+}
+
+func (p posix) Env(vars []Var) string {
+	return statements(p, vars)
+}
+
+func (posix) Wrapper() string {
+	return `kcn() {
+	command kcn "$@"
+	kcn_code=$?
+	eval "$(command kcn env --shell sh)"
+	return $kcn_code
+}
+`
+}
+
+func (posix) Kubectl() string {
+	return `unalias kubectl 2>/dev/null
+kubectl() {
+	if [ -n "${KCN_PROTECTED:-}" ]; then
+		command kcn guard -- "$@" || return $?
+	fi
+	command kubectl ${KCN_CONTEXT:+"--context=$KCN_CONTEXT"} \
+		${KCN_NAMESPACE:+"--namespace=$KCN_NAMESPACE"} "$@"
+}
+`
+}
+
+// sh has no prompt or directory change hook to attach to
+func (posix) Hook(args string) string {
+	return fmt.Sprintf("# sh has no directory change hook, run `kcn %s` by hand\n", args)
+}
@@ -0,0 +1,185 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package shell generates the code kcn's environment is sourced with, for
+// each supported shell.
+package shell
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Shell generates code for one shell.
+type Shell interface {
+	// Name is given to kcn env --shell by the wrapper function
+	Name() string
+	// Export sets and exports an environment variable
+	Export(name, value string) string
+	// Unset removes an environment variable
+	Unset(name string) string
+	// Env is the output of kcn env, which the wrapper function loads
+	Env(vars []Var) string
+	// Wrapper defines a kcn function that runs kcn, then loads the output of
+	// kcn env into the shell
+	Wrapper() string
+	// Kubectl defines a kubectl function that applies the selection, and
+	// asks kcn guard before running commands in a protected context
+	Kubectl() string
+	// Hook runs the kcn function with args when the shell's working
+	// directory changes, and once when the shell starts
+	Hook(args string) string
+}
+
+// Var is an environment variable; an empty value unsets it.
+type Var struct {
+	Name  string
+	Value string
+}
+
+var (
+	shells = map[string]Shell{}
+	// other names shells are known by, like their binary names
+	aliases = map[string]string{}
+)
+
+// register makes sh available by its name and aliases, and is called from
+// the init function of each backend.
+func register(sh Shell, names ...string) {
+	shells[sh.Name()] = sh
+	for _, v := range names {
+		aliases[v] = sh.Name()
+	}
+}
+
+// Names returns the names of all supported shells.
+func Names() []string {
+	names := make([]string, 0, len(shells))
+	for k := range shells {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Get returns the shell with the given name or alias.
+func Get(name string) (Shell, error) {
+	if v, ok := aliases[name]; ok {
+		name = v
+	}
+
+	sh, ok := shells[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported shell %s, use one of: %s",
+			name, strings.Join(Names(), ", "))
+	}
+
+	return sh, nil
+}
+
+// Detect returns the user's shell, from the parent process, then $SHELL,
+// defaulting to bash.
+func Detect() Shell {
+	for _, v := range []string{parentProcessName(), os.Getenv("SHELL")} {
+		// login shells are named like -zsh
+		name := strings.TrimPrefix(filepath.Base(v), "-")
+		if sh, err := Get(name); err == nil {
+			return sh
+		}
+	}
+
+	return shells["bash"]
+}
+
+// Resolve returns the shell named by flag, or detects it when flag is empty.
+func Resolve(flag string) (Shell, error) {
+	if len(flag) == 0 {
+		return Detect(), nil
+	}
+
+	return Get(flag)
+}
+
+// Init is the output of kcn env --init: vars, followed by the kcn and
+// kubectl wrapper functions, and the hook applying .kcn files on directory
+// changes.
+func Init(sh Shell, vars []Var) string {
+	var b strings.Builder
+
+	b.WriteString(statements(sh, vars))
+	b.WriteString("\n")
+	b.WriteString(sh.Wrapper())
+	b.WriteString(sh.Kubectl())
+	b.WriteString(sh.Hook("hook chpwd"))
+
+	return b.String()
+}
+
+// statements renders vars as one Export or Unset per line, which suits most
+// shells' Env.
+func statements(sh Shell, vars []Var) string {
+	var b strings.Builder
+
+	for _, v := range vars {
+		if len(v.Value) == 0 {
+			b.WriteString(sh.Unset(v.Name))
+		} else {
+			b.WriteString(sh.Export(v.Name, v.Value))
+		}
+	}
+
+	return b.String()
+}
+
+// parentProcessName returns the command name of the parent process, or an
+// empty string when it can't be determined.
+func parentProcessName() string {
+	ppid := strconv.Itoa(os.Getppid())
+
+	if b, err := ioutil.ReadFile(filepath.Join("/proc", ppid, "comm")); err == nil {
+		return strings.TrimSpace(string(b))
+	}
+
+	out, err := exec.Command("ps", "-o", "comm=", "-p", ppid).Output()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+var posixSafe = regexp.MustCompile(`^[[:alnum:]_@%+=:,./-]+$`)
+
+// posixQuote single quotes s for POSIX shells, when it isn't safe unquoted.
+func posixQuote(s string) string {
+	if posixSafe.MatchString(s) {
+		return s
+	}
+
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -0,0 +1,92 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shell
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update golden files")
+
+// golden compares got with testdata/name, rewriting it with -update
+func golden(t *testing.T, name string, got string) {
+	path := filepath.Join("testdata", name)
+
+	if *update {
+		if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal([]byte(got), expected) {
+		t.Errorf("%s does not match golden file\ngot:\n%s\nexpected:\n%s",
+			name, got, expected)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	initVars := []Var{
+		{"KCN_STATE_PATH", "/home/user/.cache/kcn-1234-abcdef"},
+		{"KCN_KUBECONFIG", "/home/user/.kube/config"},
+		{"KUBECONFIG", "/home/user/.cache/kcn-1234-abcdef.kubeconfig:/home/user/.kube/config"},
+	}
+	envVars := []Var{
+		{"KCN_CONTEXT", "alpha-dev"},
+		{"KCN_NAMESPACE", "it's quoted"},
+		{"KCN_PROTECTED", ""},
+	}
+
+	for _, name := range Names() {
+		sh, err := Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		golden(t, name+"-init.golden", Init(sh, initVars))
+		golden(t, name+"-env.golden", sh.Env(envVars))
+		golden(t, name+"-hook.golden", sh.Hook("hook chpwd"))
+	}
+}
+
+func TestGet(t *testing.T) {
+	if _, err := Get("tcsh"); err == nil {
+		t.Error("unsupported shell should fail")
+	}
+
+	sh, err := Get("nushell")
+	if err != nil || sh.Name() != "nu" {
+		t.Errorf("shell aliases should resolve, got %v %v", sh, err)
+	}
+
+	sh, err = Resolve("")
+	if err != nil || sh == nil {
+		t.Errorf("detected shell should be supported, got %v %v", sh, err)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package shell
+
+import (
+	"fmt"
+)
+
+type zsh struct {
+	bash
+}
+
+func init() {
+	register(zsh{})
+}
+
+func (zsh) Name() string {
+	return "zsh"
+}
+
+func (z zsh) Env(vars []Var) string {
+	return statements(z, vars)
+}
+
+func (z zsh) Wrapper() string {
+	return bashWrapper(z.Name())
+}
+
+func (zsh) Hook(args string) string {
+	return fmt.Sprintf(`_kcn_hook() {
+	kcn %s
+}
+typeset -ag chpwd_functions
+if (( ! ${chpwd_functions[(I)_kcn_hook]} )); then
+	chpwd_functions+=(_kcn_hook)
+fi
+_kcn_hook
+`, args)
+}
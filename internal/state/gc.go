@@ -0,0 +1,120 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// how often NewState sweeps for orphaned state files
+	sweepInterval = 24 * time.Hour
+	// name of the file whose mtime records the last sweep
+	sweepStamp = "kcn-gc"
+)
+
+// state files are named kcn-<shell pid>-<random>
+var stateFileName = regexp.MustCompile(`^kcn-([0-9]+)-[[:alnum:]]+$`)
+
+// files kept alongside each state file
+var sessionSuffixes = []string{"", ".kubeconfig", ".lock"}
+
+// Orphan is a state file whose session is over.
+type Orphan struct {
+	Path   string
+	Reason string
+}
+
+// Dir returns the directory state files are created in.
+func Dir() (string, error) {
+	return os.UserCacheDir()
+}
+
+// Collect finds state files in dir whose shell is no longer running, or that
+// haven't been modified for maxAge when it is non-zero. Unless dryRun is set,
+// they are removed along with their kubeconfig and lock files. The state file
+// at keep is never collected.
+func Collect(dir string, maxAge time.Duration, keep string, dryRun bool) ([]Orphan, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var orphans []Orphan
+	for _, v := range files {
+		m := stateFileName.FindStringSubmatch(v.Name())
+		if m == nil || v.IsDir() {
+			continue
+		}
+
+		path := filepath.Join(dir, v.Name())
+		if path == keep {
+			continue
+		}
+
+		var reason string
+		pid, _ := strconv.Atoi(m[1])
+		if !processAlive(pid) {
+			reason = fmt.Sprintf("shell %d is not running", pid)
+		} else if maxAge > 0 && time.Since(v.ModTime()) > maxAge {
+			reason = fmt.Sprintf("unused since %s", v.ModTime().Format(time.RFC3339))
+		} else {
+			continue
+		}
+
+		if !dryRun {
+			for _, suffix := range sessionSuffixes {
+				err := os.Remove(path + suffix)
+				if err != nil && !os.IsNotExist(err) {
+					return orphans, err
+				}
+			}
+		}
+
+		orphans = append(orphans, Orphan{Path: path, Reason: reason})
+	}
+
+	return orphans, nil
+}
+
+// sweep collects orphaned state files in dir, at most once per
+// sweepInterval. Errors are ignored, as sweeping is opportunistic.
+func sweep(dir string, maxAge time.Duration, keep string) {
+	stamp := filepath.Join(dir, sweepStamp)
+
+	if info, err := os.Stat(stamp); err == nil && time.Since(info.ModTime()) < sweepInterval {
+		return
+	}
+
+	if err := ioutil.WriteFile(stamp, nil, 0644); err != nil {
+		return
+	}
+	now := time.Now()
+	os.Chtimes(stamp, now, now)
+
+	Collect(dir, maxAge, keep, false)
+}
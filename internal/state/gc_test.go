@@ -0,0 +1,94 @@
+// Copyright © 2018 Jesse Lang
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package state
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCollect(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kcn-gc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// a pid above the kernel's maximum is never running
+	dead := filepath.Join(dir, "kcn-99999999-abcdef")
+	alive := filepath.Join(dir, fmt.Sprintf("kcn-%d-abcdef", os.Getpid()))
+	stale := filepath.Join(dir, fmt.Sprintf("kcn-%d-ghijkl", os.Getpid()))
+	current := filepath.Join(dir, "kcn-99999999-mnopqr")
+	other := filepath.Join(dir, "unrelated")
+
+	for _, v := range []string{dead, dead + ".kubeconfig", dead + ".lock",
+		alive, stale, current, other} {
+		if err := ioutil.WriteFile(v, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(stale, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	orphans, err := Collect(dir, 24*time.Hour, current, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, v := range orphans {
+		paths = append(paths, v.Path)
+	}
+	sort.Strings(paths)
+	expected := []string{dead, stale}
+	sort.Strings(expected)
+	if fmt.Sprint(paths) != fmt.Sprint(expected) {
+		t.Fatalf("expected orphans %v, got %v", expected, paths)
+	}
+
+	if _, err := os.Stat(dead); err != nil {
+		t.Error("dry run should not remove files")
+	}
+
+	if _, err := Collect(dir, 0, current, false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range []string{dead, dead + ".kubeconfig", dead + ".lock"} {
+		if _, err := os.Stat(v); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed", v)
+		}
+	}
+
+	for _, v := range []string{alive, stale, current, other} {
+		if _, err := os.Stat(v); err != nil {
+			t.Errorf("%s should be kept: %s", v, err)
+		}
+	}
+}
@@ -23,6 +23,7 @@ package state
 import (
 	"encoding/json"
 	"errors"
+	"time"
 )
 
 var (
@@ -34,6 +35,23 @@ var (
 type Element struct {
 	Context   string `json:"context"`
 	Namespace string `json:"namespace"`
+	// unix time the element was selected, absent from older state files
+	Time int64 `json:"time,omitempty"`
+	// the alias it was selected by, if any
+	Alias string `json:"alias,omitempty"`
+	// where the namespace came from, one of the From constants, absent from
+	// older state files
+	NamespaceFrom string `json:"namespace_from,omitempty"`
+}
+
+// Timestamp returns when the element was selected, or the zero time when
+// that isn't known.
+func (e Element) Timestamp() time.Time {
+	if e.Time == 0 {
+		return time.Time{}
+	}
+
+	return time.Unix(e.Time, 0)
 }
 
 // basic stack that serializes to and from a JSON array
@@ -45,6 +63,11 @@ func (s *stack) Length() int {
 	return len(s.data)
 }
 
+// Elements returns a copy of the stack's elements, top first.
+func (s *stack) Elements() []Element {
+	return append([]Element(nil), s.data...)
+}
+
 func (s *stack) Clear() {
 	s.data = nil
 }
@@ -97,6 +120,26 @@ func (s *stack) Swap() error {
 	return nil
 }
 
+// Raise moves the element at index n to the top of the stack.
+func (s *stack) Raise(n int) error {
+	if n < 0 || n >= len(s.data) {
+		return errStackInsufficient
+	}
+
+	raised := s.data[n]
+	copy(s.data[1:n+1], s.data[:n])
+	s.data[0] = raised
+
+	return nil
+}
+
+// Truncate evicts the oldest elements beyond max.
+func (s *stack) Truncate(max int) {
+	if max >= 0 && len(s.data) > max {
+		s.data = s.data[:max]
+	}
+}
+
 func (s stack) MarshalJSON() ([]byte, error) {
 	jsonValue, err := json.Marshal(s.data)
 	if err != nil {
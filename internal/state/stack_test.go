@@ -274,3 +274,88 @@ func TestStackUnmarshaling(t *testing.T) {
 		}
 	}
 }
+
+func TestStackRaise(t *testing.T) {
+	input := elementFixture()
+	expected := []Element{input[2], input[0], input[1]}
+
+	var s stack
+
+	// insert input data in reverse order
+	for i := len(input) - 1; i >= 0; i-- {
+		s.Push(input[i])
+	}
+
+	if err := s.Raise(3); err != errStackInsufficient {
+		t.Errorf("raise beyond the stack should fail due to insufficient elements %v", err)
+	}
+
+	err := s.Raise(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, v := range expected {
+		popped, err := s.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *popped != v {
+			t.Errorf("%v popped from stack does not match expected %v",
+				*popped, v)
+		}
+	}
+}
+
+func TestStackTruncate(t *testing.T) {
+	input := elementFixture()
+
+	var s stack
+
+	// insert input data in reverse order
+	for i := len(input) - 1; i >= 0; i-- {
+		s.Push(input[i])
+	}
+
+	s.Truncate(5)
+	if s.Length() != 3 {
+		t.Errorf("truncate beyond length should keep all elements, got %d", s.Length())
+	}
+
+	s.Truncate(2)
+	if s.Length() != 2 {
+		t.Fatalf("stack length should be truncated to 2, got %d", s.Length())
+	}
+
+	// the oldest element is evicted
+	for _, v := range input[:2] {
+		popped, err := s.Pop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if *popped != v {
+			t.Errorf("%v popped from stack does not match expected %v",
+				*popped, v)
+		}
+	}
+}
+
+func TestElementTimestamp(t *testing.T) {
+	var e Element
+	if err := json.Unmarshal([]byte(`{"context":"alpha-dev","namespace":"default"}`), &e); err != nil {
+		t.Fatal(err)
+	}
+	if !e.Timestamp().IsZero() {
+		t.Error("element without time should have a zero timestamp")
+	}
+
+	e.Time = 1600000000
+	b, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"context":"alpha-dev","namespace":"default","time":1600000000}`
+	if string(b) != expected {
+		t.Errorf("%s does not match expected %s", b, expected)
+	}
+}
@@ -21,108 +21,403 @@
 package state
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
-
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jesselang/kcn/internal/audit"
+	"github.com/jesselang/kcn/internal/config"
+	"github.com/jesselang/kcn/internal/fsutil"
+	"github.com/jesselang/kcn/internal/kubeconfig"
 	"github.com/jesselang/kcn/internal/kubectl"
+	"github.com/jesselang/kcn/internal/labels"
+	"github.com/jesselang/kcn/internal/match"
+	"github.com/jesselang/kcn/internal/recent"
+)
+
+// replaced in tests
+var now = time.Now
+
+// where the namespace of an element came from
+const (
+	FromArgument   = "argument"
+	FromAlias      = "alias"
+	FromSession    = "session"
+	FromRecent     = "recent"
+	FromKubeconfig = "kubeconfig"
+	FromConfig     = "config"
+	FromDefault    = "default"
 )
 
 type State struct {
 	Stack stack `json:"stack"`
+	// selection pushed by a .kcn file, while within its directory tree
+	Dir *DirElement `json:"dir,omitempty"`
+
+	// only accept exact context and namespace names in Update
+	Exact bool `json:"-"`
+	// settings from the config file
+	Config config.Config `json:"-"`
+	// asked before switching into a protected context, switching is refused
+	// when nil
+	Confirm func(context string) bool `json:"-"`
+	// namespaces used across sessions, consulted and recorded when set
+	Recent *recent.Store `json:"-"`
+	// changes of selection are appended to it when set
+	Audit *audit.Log `json:"-"`
 
 	path string
 	k    kubectl.Kubectl
 }
 
-func NewState(k kubectl.Kubectl) (*State, error) {
-	cache, err := os.UserCacheDir()
+// DirElement records the element pushed for a .kcn file.
+type DirElement struct {
+	File    string  `json:"file"`
+	Element Element `json:"element"`
+}
+
+func NewState(ctx context.Context, k kubectl.Kubectl, cfg config.Config) (*State, error) {
+	cache, err := Dir()
 	if err != nil {
 		return nil, err
 	}
 
 	initial := State{
-		path: fmt.Sprintf("%s/kcn-%d-%s", cache, os.Getppid(), randString(6)),
+		Config: cfg,
+		path:   fmt.Sprintf("%s/kcn-%d-%s", cache, os.Getppid(), randString(6)),
 	}
 
 	if k == nil {
-		initial.k = kubectl.NewKubectl()
+		k = kubectl.NewKubectl()
 	}
+	initial.k = k
+
+	sweep(cache, cfg.GC.MaxAge, initial.path)
+
+	return &initial, initial.Write(ctx)
+}
 
-	return &initial, initial.Write()
+// NewEmpty returns a state without a file, for commands that work outside of
+// a session. It resolves selections like any other, but can't be written.
+func NewEmpty(k kubectl.Kubectl, cfg config.Config) *State {
+	return &State{Config: cfg, k: k}
 }
 
-func ReadState(path string) (*State, error) {
+func ReadState(path string, k kubectl.Kubectl) (*State, error) {
 	if len(path) == 0 {
 		return nil, errors.New("no state path given")
 	}
 
-	file, err := os.Open(path)
-	if err != nil {
+	var s State
+	if err := s.load(path); err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	b, err := ioutil.ReadAll(file)
+	s.path = path
+	s.k = k
+	return &s, nil
+}
+
+// load reads the state file at path into s. A corrupt state file, left by
+// an earlier version that wrote in place, is recovered as an empty stack.
+func (s *State) load(path string) error {
+	b, err := ioutil.ReadFile(path)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	var s State
-	err = json.Unmarshal(b, &s)
+	var loaded State
+	if err := json.Unmarshal(b, &loaded); err != nil {
+		fmt.Fprintf(os.Stderr,
+			"kcn: state file %s is corrupt (%s), starting with an empty stack\n",
+			path, err)
+		loaded = State{}
+	}
+
+	s.Stack = loaded.Stack
+	s.Dir = loaded.Dir
+	return nil
+}
+
+// locked runs fn holding the state file's lock, after reloading the stack in
+// case another kcn process in the same session changed it.
+func (s *State) locked(fn func() error) error {
+	if len(s.path) == 0 {
+		return fmt.Errorf("state path not set")
+	}
+
+	unlock, err := fsutil.Lock(s.path + ".lock")
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer unlock()
 
-	s.path = path
-	s.k = kubectl.NewKubectl()
-	return &s, nil
+	if err := s.load(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return fn()
 }
 
 func (s *State) Path() string {
 	return s.path
 }
 
-func (s *State) Clear() error {
-	s.Stack.Clear()
+// Kubectl returns the implementation given to ReadState, or the default one.
+// It's constructed when first needed, so reading state alone stays cheap.
+func (s *State) Kubectl() kubectl.Kubectl {
+	if s.k == nil {
+		s.k = kubectl.NewKubectl()
+	}
+
+	return s.k
+}
 
-	return s.Write()
+// KubeconfigPath returns the path of the per-session kubeconfig, which
+// selects the context and namespace at the top of the stack.
+func (s *State) KubeconfigPath() string {
+	return s.path + ".kubeconfig"
 }
 
-func (s *State) Write() error {
+func (s *State) Clear(ctx context.Context) error {
+	return s.locked(func() error {
+		prev := s.top()
+		s.Stack.Clear()
+
+		if err := s.Write(ctx); err != nil {
+			return err
+		}
+		s.record(audit.ActionClear, prev, nil)
+
+		return nil
+	})
+}
+
+func (s *State) Write(ctx context.Context) error {
 	if len(s.path) == 0 {
 		return fmt.Errorf("state path not set")
 	}
 
-	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE, 0644)
+	b, err := json.Marshal(s)
 	if err != nil {
 		return err
 	}
-	defer file.Close()
 
-	file.Truncate(0)
+	if err := fsutil.WriteFile(s.path, b, 0644); err != nil {
+		return err
+	}
 
-	b, err := json.Marshal(s)
+	return s.writeKubeconfig(ctx)
+}
+
+// writeKubeconfig writes a minimal kubeconfig meant to be layered over the
+// user's own, so that any kubernetes client honors the session's selection.
+func (s *State) writeKubeconfig(ctx context.Context) error {
+	curr, _ := s.Stack.Peek()
+	return s.WriteKubeconfig(ctx, s.KubeconfigPath(), curr)
+}
+
+// WriteKubeconfig writes a kubeconfig to path that selects curr, which may
+// be nil to select nothing, when layered over the user's own.
+func (s *State) WriteKubeconfig(ctx context.Context, path string, curr *Element) error {
+	c := kubeconfig.Config{
+		APIVersion: "v1",
+		Kind:       "Config",
+	}
+
+	if curr != nil && len(curr.Context) > 0 {
+		c.CurrentContext = curr.Context
+
+		// the context is redefined to carry the namespace, which requires
+		// its cluster and user from the user's kubeconfig
+		details, err := s.Kubectl().GetContext(ctx, curr.Context)
+		if err == nil {
+			c.Contexts = []kubeconfig.NamedContext{
+				{
+					Name: curr.Context,
+					Context: kubeconfig.Context{
+						Cluster:   details.Cluster,
+						User:      details.User,
+						Namespace: curr.Namespace,
+					},
+				},
+			}
+		}
+	}
+
+	return kubeconfig.Write(path, &c)
+}
+
+func (st *State) Update(ctx context.Context, args ...string) error {
+	return st.locked(func() error {
+		return st.update(ctx, args...)
+	})
+}
+
+func (st *State) update(ctx context.Context, args ...string) error {
+	prev := st.top()
+
+	context, namespace, err := st.expand(args)
 	if err != nil {
 		return err
 	}
 
-	n, err := file.Write(b)
+	if n, ok := historyIndex(context); ok {
+		if len(namespace) > 0 {
+			return fmt.Errorf("a namespace can't be given with %s", context)
+		}
+		if err := st.jump(ctx, n); err != nil {
+			return err
+		}
+		st.record(audit.ActionJump, prev, args)
+		return nil
+	}
+
+	if context == "-" && len(namespace) == 0 {
+		if st.Stack.Length() == 0 {
+			return errors.New("no previous state, try `kcn .`")
+		}
+
+		if prev, err := st.Stack.PeekPrev(); err == nil {
+			if err := st.confirm(prev.Context); err != nil {
+				return err
+			}
+		}
+
+		st.Stack.Swap()
+		st.stamp()
+		st.remember()
+		if err := st.Write(ctx); err != nil {
+			return err
+		}
+		st.record(audit.ActionSwap, prev, args)
+		return nil
+	}
+
+	next, err := st.resolve(ctx, context, namespace)
 	if err != nil {
 		return err
 	}
-	if n != len(b) {
-		return fmt.Errorf("could not fully write to state file")
+	st.byAlias(next, args)
+
+	if err := st.confirm(next.Context); err != nil {
+		return err
+	}
+
+	next.Time = now().Unix()
+	st.Stack.Push(*next)
+	if st.Config.History.MaxDepth > 0 {
+		st.Stack.Truncate(st.Config.History.MaxDepth)
 	}
+	st.remember()
+
+	if err := st.Write(ctx); err != nil {
+		return err
+	}
+	st.record(audit.ActionSwitch, prev, args)
 
 	return nil
 }
 
-func (st *State) Update(args ...string) error {
+// Resolve returns the element that Update would select for args, without
+// changing the stack or asking for confirmation.
+func (st *State) Resolve(ctx context.Context, args ...string) (*Element, error) {
+	context, namespace, err := st.expand(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if n, ok := historyIndex(context); ok {
+		if len(namespace) > 0 {
+			return nil, fmt.Errorf("a namespace can't be given with %s", context)
+		}
+
+		elements := st.Stack.Elements()
+		if n >= len(elements) {
+			return nil, fmt.Errorf("no element @%d in history of %d", n, len(elements))
+		}
+		e := elements[n]
+		return &e, nil
+	}
+
+	if context == "-" && len(namespace) == 0 {
+		prev, err := st.Stack.PeekPrev()
+		if err != nil {
+			return nil, errors.New("no previous state")
+		}
+		e := *prev
+		return &e, nil
+	}
+
+	next, err := st.resolve(ctx, context, namespace)
+	if err != nil {
+		return nil, err
+	}
+	st.byAlias(next, args)
+
+	return next, nil
+}
+
+// Labeled returns the contexts whose configured labels match sel.
+func (st *State) Labeled(ctx context.Context, sel labels.Selector) ([]string, error) {
+	ctxList, err := st.Kubectl().GetContextList(ctx)
+	if err != nil {
+		return nil, errors.New("could not get context list")
+	}
+
+	var found []string
+	for _, v := range ctxList {
+		if sel.Matches(st.Config.ContextLabels(v)) {
+			found = append(found, v)
+		}
+	}
+
+	return found, nil
+}
+
+// ByLabels returns the single context whose configured labels match sel.
+func (st *State) ByLabels(ctx context.Context, sel labels.Selector) (string, error) {
+	found, err := st.Labeled(ctx, sel)
+	if err != nil {
+		return "", err
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no context labeled %s found", sel)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("context labeled %s is ambiguous, could be: %s",
+			sel, strings.Join(found, ", "))
+	}
+}
+
+// byAlias records the alias next was selected by, if args[0] is one.
+func (st *State) byAlias(next *Element, args []string) {
+	alias, ok := st.Config.Aliases[args[0]]
+	if !ok {
+		return
+	}
+
+	next.Alias = args[0]
+	if len(args) == 1 && len(alias.Namespace) > 0 {
+		next.NamespaceFrom = FromAlias
+	}
+}
+
+// expand splits args into a context and namespace. Aliases are resolved
+// before validation, and only supply a namespace when none was given.
+func (st *State) expand(args []string) (string, string, error) {
 	if len(args) == 0 {
-		return errors.New("no args given")
+		return "", "", errors.New("no args given")
 	}
 
 	var context, namespace string
@@ -131,91 +426,346 @@ func (st *State) Update(args ...string) error {
 	}
 	context = args[0]
 
-	ctxList, err := st.k.GetContextList()
+	if alias, ok := st.Config.Aliases[context]; ok {
+		context = alias.Context
+		if len(namespace) == 0 {
+			namespace = alias.Namespace
+		}
+	}
+
+	return context, namespace, nil
+}
+
+// resolve validates context and namespace, where context may be . for the
+// current context and - for the previous element's.
+func (st *State) resolve(ctx context.Context, context, namespace string) (*Element, error) {
+	ctxList, err := st.Kubectl().GetContextList(ctx)
 	if err != nil {
-		return errors.New("could not get context list")
+		return nil, errors.New("could not get context list")
 	}
 
 	next := &Element{}
 
 	if context == "." {
 		if st.Stack.Length() == 0 {
-			next.Context, err = st.k.GetCurrentContext()
+			next.Context, err = st.Kubectl().GetCurrentContext(ctx)
 			if err != nil {
-				return errors.New("could not get current context")
+				return nil, errors.New("could not get current context")
 			}
-
-			//next.Namespace = kubectl.DefaultNamespace
 		} else {
 			curr, err := st.Stack.Peek()
 			if err != nil {
-				return err
+				return nil, err
 			}
 			next.Context = curr.Context
 		}
 	} else if context == "-" {
 		if st.Stack.Length() == 0 {
-			return errors.New("no previous state, try `kcn .`")
-		} else {
-			if len(namespace) == 0 {
-				st.Stack.Swap()
-				return st.Write()
-			}
+			return nil, errors.New("no previous state, try `kcn .`")
+		}
 
-			next, err = st.Stack.Peek()
-			if err != nil {
-				return err
-			}
+		curr, err := st.Stack.Peek()
+		if err != nil {
+			return nil, err
 		}
+		next.Context = curr.Context
 	} else {
-		found := false
-		for _, v := range ctxList {
-			if context == v {
-				next.Context = context
-				found = true
-			}
+		next.Context, err = st.selectOne("context", context, ctxList, "")
+		if err != nil {
+			return nil, err
 		}
+	}
 
-		if !found {
-			return fmt.Errorf("context %s not found", context)
+	if len(namespace) == 0 {
+		next.Namespace, next.NamespaceFrom = st.defaultNamespace(ctx, next.Context)
+		return next, nil
+	}
+
+	if namespace == "-" {
+		prev, err := st.Stack.PeekPrev()
+		if err != nil {
+			return nil, err
 		}
+		namespace = prev.Namespace
 	}
 
-	nsList, err := st.k.GetNamespaceList(next.Context)
+	next.NamespaceFrom = FromArgument
+
+	nsList, err := st.Kubectl().GetNamespaceList(ctx, next.Context)
 	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		// an unreachable cluster shouldn't prevent switching to it
 		fmt.Fprintf(os.Stderr,
-			"kcn: could not get namespace list for context %s,"+
-				" falling back to %s\n",
-			next.Context, kubectl.DefaultNamespace)
-		next.Namespace = kubectl.DefaultNamespace
+			"kcn: could not get namespace list for context %s (%s),"+
+				" using namespace %s unchecked\n",
+			next.Context, err, namespace)
+		next.Namespace = namespace
+		return next, nil
 	}
 
-	if len(namespace) == 0 || st.Stack.Length() == 0 {
-		next.Namespace = kubectl.DefaultNamespace
-	} else {
-		if namespace == "-" {
-			prev, err := st.Stack.PeekPrev()
-			if err != nil {
-				return err
+	if len(match.Select(namespace, nsList, st.Exact)) == 0 {
+		// a cached list may predate the namespace
+		if r, ok := st.Kubectl().(kubectl.Refresher); ok {
+			if fresh, err := r.RefreshNamespaceList(ctx, next.Context); err == nil {
+				nsList = fresh
 			}
-			namespace = prev.Namespace
 		}
+	}
 
-		found := false
-		for _, v := range nsList {
-			if namespace == v {
-				next.Namespace = namespace
-				found = true
+	next.Namespace, err = st.selectOne("namespace", namespace, nsList,
+		" in context "+next.Context)
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// defaultNamespace returns the namespace for context when none is given, and
+// where it came from: the last one used with it in this session, then in any
+// session, the namespace of its kubeconfig context, the one configured for
+// it, or default.
+func (st *State) defaultNamespace(ctx context.Context, context string) (string, string) {
+	for _, v := range st.Stack.Elements() {
+		if v.Context == context && len(v.Namespace) > 0 {
+			return v.Namespace, FromSession
+		}
+	}
+
+	if st.Recent != nil {
+		if r, err := st.Recent.Load(); err == nil && len(r.Namespace(context)) > 0 {
+			return r.Namespace(context), FromRecent
+		}
+	}
+
+	if details, err := st.Kubectl().GetContext(ctx, context); err == nil && len(details.Namespace) > 0 {
+		return details.Namespace, FromKubeconfig
+	}
+
+	if namespace := st.Config.ContextNamespace(context); len(namespace) > 0 {
+		return namespace, FromConfig
+	}
+
+	return kubectl.DefaultNamespace, FromDefault
+}
+
+// Pop discards the top element, returning to the one below it. Popping the
+// last element leaves the session without a selection.
+func (st *State) Pop(ctx context.Context) error {
+	return st.locked(func() error {
+		if st.Stack.Length() == 0 {
+			return errors.New("stack is empty")
+		}
+
+		if prev, err := st.Stack.PeekPrev(); err == nil {
+			if err := st.confirm(prev.Context); err != nil {
+				return err
 			}
 		}
 
-		if !found {
-			return fmt.Errorf("namespace %s not found in context %s",
-				namespace, next.Context)
+		popped, err := st.Stack.Pop()
+		if err != nil {
+			return err
 		}
+		if st.Dir != nil && st.Dir.Element == *popped {
+			st.Dir = nil
+		}
+		st.stamp()
+		st.remember()
+
+		if err := st.Write(ctx); err != nil {
+			return err
+		}
+		st.record(audit.ActionPop, popped, nil)
+
+		return nil
+	})
+}
+
+// historyIndex parses @N, which selects the Nth previous element.
+func historyIndex(arg string) (int, bool) {
+	if !strings.HasPrefix(arg, "@") {
+		return 0, false
 	}
 
-	st.Stack.Push(*next)
+	n, err := strconv.Atoi(arg[1:])
+	if err != nil || n < 0 {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// jump moves the Nth previous element to the top of the stack, which for
+// N=1 is the same as kcn -.
+func (st *State) jump(ctx context.Context, n int) error {
+	elements := st.Stack.Elements()
+	if n >= len(elements) {
+		return fmt.Errorf("no element @%d in history of %d", n, len(elements))
+	}
+
+	if err := st.confirm(elements[n].Context); err != nil {
+		return err
+	}
 
-	return st.Write()
+	if err := st.Stack.Raise(n); err != nil {
+		return err
+	}
+	st.stamp()
+	st.remember()
+
+	return st.Write(ctx)
+}
+
+// top returns a copy of the top element, or nil when the stack is empty.
+func (st *State) top() *Element {
+	curr, err := st.Stack.Peek()
+	if err != nil {
+		return nil
+	}
+
+	e := *curr
+	return &e
+}
+
+// record appends the change from prev to the top element to st.Audit. A log
+// that can't be written is reported, but doesn't undo the change.
+func (st *State) record(action string, prev *Element, args []string) {
+	if st.Audit == nil {
+		return
+	}
+
+	e := audit.NewEntry(action, filepath.Base(st.path))
+	e.Args = args
+	e.Previous = selection(prev)
+	e.Current = selection(st.top())
+
+	if err := st.Audit.Append(e); err != nil {
+		fmt.Fprintf(os.Stderr, "kcn: could not write audit log: %s\n", err)
+	}
+}
+
+func selection(e *Element) *audit.Selection {
+	if e == nil {
+		return nil
+	}
+
+	return &audit.Selection{
+		Context:       e.Context,
+		Namespace:     e.Namespace,
+		NamespaceFrom: e.NamespaceFrom,
+		Alias:         e.Alias,
+	}
+}
+
+// remember records the top element in st.Recent, for other sessions.
+func (st *State) remember() {
+	if st.Recent == nil {
+		return
+	}
+
+	if curr, err := st.Stack.Peek(); err == nil {
+		if err := st.Recent.Record(curr.Context, curr.Namespace); err != nil {
+			fmt.Fprintf(os.Stderr, "kcn: could not record recent use: %s\n", err)
+		}
+	}
+}
+
+// stamp records that the top element was selected now.
+func (st *State) stamp() {
+	if curr, err := st.Stack.Peek(); err == nil {
+		curr.Time = now().Unix()
+	}
+}
+
+// confirm asks before switching into a protected context from another one.
+func (st *State) confirm(context string) error {
+	if !st.Config.IsProtected(context) {
+		return nil
+	}
+
+	if curr, err := st.Stack.Peek(); err == nil && curr.Context == context {
+		return nil
+	}
+
+	if st.Confirm == nil || !st.Confirm(context) {
+		return fmt.Errorf("context %s is protected, not switching", context)
+	}
+
+	return nil
+}
+
+// Chdir follows the shell into a directory. When file, the .kcn file
+// governing the directory, differs from the one last applied, the element
+// pushed for the old file is popped if it's still on top, and args selected
+// by the new file are pushed. file is empty outside any .kcn tree.
+func (st *State) Chdir(ctx context.Context, file string, args ...string) error {
+	// outside any .kcn tree, as after most cd's, there's nothing to do
+	if st.Dir == nil && len(file) == 0 {
+		return nil
+	}
+
+	return st.locked(func() error {
+		if st.Dir == nil && len(file) == 0 {
+			return nil
+		}
+		if st.Dir != nil && st.Dir.File == file {
+			return nil
+		}
+
+		var left *Element
+		if st.Dir != nil {
+			if curr, err := st.Stack.Peek(); err == nil && *curr == st.Dir.Element {
+				left, _ = st.Stack.Pop()
+			}
+			st.Dir = nil
+		}
+
+		if len(file) == 0 {
+			if err := st.Write(ctx); err != nil {
+				return err
+			}
+			if left != nil {
+				st.record(audit.ActionPop, left, nil)
+			}
+			return nil
+		}
+		if left != nil {
+			st.record(audit.ActionPop, left, nil)
+		}
+
+		if err := st.update(ctx, args...); err != nil {
+			// written so that leaving the previous tree sticks
+			st.Write(ctx)
+			return err
+		}
+
+		curr, err := st.Stack.Peek()
+		if err != nil {
+			return err
+		}
+		st.Dir = &DirElement{File: file, Element: *curr}
+
+		return st.Write(ctx)
+	})
+}
+
+// selectOne finds the single entry of list that name refers to, by exact,
+// prefix, substring or fuzzy match unless st.Exact is set. where is appended
+// to the name in errors.
+func (st *State) selectOne(kind, name string, list []string, where string) (string, error) {
+	found := match.Select(name, list, st.Exact)
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("%s %s not found%s", kind, name, where)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("%s %s is ambiguous%s, could be: %s",
+			kind, name, where, strings.Join(found, ", "))
+	}
 }
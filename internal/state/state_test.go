@@ -21,21 +21,748 @@
 package state
 
 import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/jesselang/kcn/internal/audit"
+	"github.com/jesselang/kcn/internal/config"
+	"github.com/jesselang/kcn/internal/kubeconfig"
+	"github.com/jesselang/kcn/internal/kubectl"
+	"github.com/jesselang/kcn/internal/labels"
+	"github.com/jesselang/kcn/internal/recent"
 )
 
+var ctx = context.Background()
+
+// stateFixture returns an empty state backed by a temporary file and the
+// kubectl mock
+func stateFixture(t *testing.T) *State {
+	dir, err := ioutil.TempDir("", "kcn-state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	return &State{
+		path: filepath.Join(dir, "state"),
+		k:    kubectl.NewMock(),
+	}
+}
+
 func TestReadState(t *testing.T) {
-	_, err := ReadState("")
+	_, err := ReadState("", nil)
 	if err == nil {
 		t.Error("empty state path should fail")
 	}
 
-	_, err = ReadState("nonexistent/path")
+	_, err = ReadState("nonexistent/path", nil)
 	if err == nil {
 		t.Error("non-existent file at state path should fail")
 	}
 }
 
+func TestNewEmpty(t *testing.T) {
+	st := NewEmpty(kubectl.NewMock(), config.Config{})
+
+	e, err := st.Resolve(ctx, ".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.Context != "bravo-stage" {
+		t.Errorf("expected kubeconfig's current context, got %+v", e)
+	}
+
+	if _, err := st.Resolve(ctx, "-"); err == nil {
+		t.Error("resolving - without a session should fail")
+	}
+	if err := st.Update(ctx, "alpha-dev"); err == nil {
+		t.Error("updating without a state file should fail")
+	}
+}
+
+func TestReadStateCorrupt(t *testing.T) {
+	st := stateFixture(t)
+
+	if err := ioutil.WriteFile(st.path, []byte(`{"stack":[{"con`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	recovered, err := ReadState(st.path, kubectl.NewMock())
+	if err != nil {
+		t.Fatalf("corrupt state should be recovered, got %s", err)
+	}
+	if recovered.Stack.Length() != 0 {
+		t.Error("recovered state should have an empty stack")
+	}
+
+	if err := recovered.Update(ctx, "alpha-dev"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateConcurrent(t *testing.T) {
+	st := stateFixture(t)
+	if err := st.Write(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// every update is kept, as each reloads the stack under the lock
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			other, err := ReadState(st.path, kubectl.NewMock())
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if err := other.Update(ctx, "alpha-dev"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	final, err := ReadState(st.path, kubectl.NewMock())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if final.Stack.Length() != 10 {
+		t.Errorf("expected 10 elements, got %d", final.Stack.Length())
+	}
+}
+
+func TestUpdateMatching(t *testing.T) {
+	cases := []struct {
+		args      []string
+		exact     bool
+		context   string
+		namespace string
+		err       string
+	}{
+		{[]string{"alpha-dev", "app-a"}, false, "alpha-dev", "app-a", ""},
+		{[]string{"alpha", "kube"}, false, "alpha-dev", "kube-system", ""},
+		{[]string{"stage", "app-e"}, false, "bravo-stage", "app-e", ""},
+		{[]string{"dp", "apz"}, false, "delta-prod", "app-z", ""},
+		{[]string{"e"}, false, "", "", "context e is ambiguous"},
+		{[]string{"delta", "app"}, false, "", "",
+			"namespace app is ambiguous in context delta-prod"},
+		{[]string{"alpha"}, true, "", "", "context alpha not found"},
+		{[]string{"alpha-dev", "kube"}, true, "", "",
+			"namespace kube not found in context alpha-dev"},
+	}
+
+	for _, c := range cases {
+		st := stateFixture(t)
+		st.Exact = c.exact
+
+		err := st.Update(ctx, c.args...)
+		if len(c.err) > 0 {
+			if err == nil || !strings.HasPrefix(err.Error(), c.err) {
+				t.Errorf("%v: expected error %q, got %v", c.args, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %s", c.args, err)
+			continue
+		}
+
+		curr, err := st.Stack.Peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if curr.Context != c.context || curr.Namespace != c.namespace {
+			t.Errorf("%v: expected %s/%s, got %s/%s", c.args,
+				c.context, c.namespace, curr.Context, curr.Namespace)
+		}
+	}
+}
+
+func TestUpdateAlias(t *testing.T) {
+	st := stateFixture(t)
+	st.Config.Aliases = map[string]config.Alias{
+		"prod": {Context: "delta-prod", Namespace: "app-x"},
+		"dev":  {Context: "alpha-dev"},
+	}
+
+	cases := []struct {
+		args      []string
+		context   string
+		namespace string
+	}{
+		{[]string{"prod"}, "delta-prod", "app-x"},
+		{[]string{"prod", "app-y"}, "delta-prod", "app-y"},
+		{[]string{"dev"}, "alpha-dev", kubectl.DefaultNamespace},
+		{[]string{"dev", "app-b"}, "alpha-dev", "app-b"},
+	}
+
+	for _, c := range cases {
+		if err := st.Update(ctx, c.args...); err != nil {
+			t.Errorf("%v: %s", c.args, err)
+			continue
+		}
+
+		curr, err := st.Stack.Peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if curr.Context != c.context || curr.Namespace != c.namespace {
+			t.Errorf("%v: expected %s/%s, got %s/%s", c.args,
+				c.context, c.namespace, curr.Context, curr.Namespace)
+		}
+		if curr.Alias != c.args[0] {
+			t.Errorf("%v: expected alias %s to be recorded, got %q", c.args, c.args[0], curr.Alias)
+		}
+	}
+
+	if err := st.Update(ctx, "delta-prod"); err != nil {
+		t.Fatal(err)
+	}
+	if curr, _ := st.Stack.Peek(); curr.Alias != "" {
+		t.Errorf("expected no alias, got %s", curr.Alias)
+	}
+}
+
+func TestUpdateProtected(t *testing.T) {
+	st := stateFixture(t)
+	st.Config.Protected = []string{"*-prod"}
+
+	var asked []string
+	answer := false
+	st.Confirm = func(context string) bool {
+		asked = append(asked, context)
+		return answer
+	}
+
+	if err := st.Update(ctx, "alpha-dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.Update(ctx, "delta-prod"); err == nil {
+		t.Error("declined switch to protected context should fail")
+	}
+
+	answer = true
+	if err := st.Update(ctx, "delta-prod"); err != nil {
+		t.Fatal(err)
+	}
+
+	// staying in the protected context doesn't ask again
+	if err := st.Update(ctx, ".", "kube-system"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := st.Update(ctx, "bravo-stage"); err != nil {
+		t.Fatal(err)
+	}
+
+	// swapping back into the protected context asks again
+	answer = false
+	if err := st.Update(ctx, "-"); err == nil {
+		t.Error("declined swap to protected context should fail")
+	}
+
+	expected := []string{"delta-prod", "delta-prod", "delta-prod"}
+	if !reflect.DeepEqual(asked, expected) {
+		t.Errorf("expected confirmations for %v, got %v", expected, asked)
+	}
+
+	st.Confirm = nil
+	if err := st.Update(ctx, "delta-prod"); err == nil {
+		t.Error("switch to protected context without confirmation should fail")
+	}
+}
+
+func TestChdir(t *testing.T) {
+	st := stateFixture(t)
+
+	if err := st.Update(ctx, "alpha-dev", "app-a"); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := func(context, namespace string) {
+		t.Helper()
+
+		curr, err := st.Stack.Peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if curr.Context != context || curr.Namespace != namespace {
+			t.Errorf("expected %s/%s, got %s/%s",
+				context, namespace, curr.Context, curr.Namespace)
+		}
+	}
+
+	// outside any tree, nothing is written
+	if err := os.Remove(st.path); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Chdir(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(st.path); !os.IsNotExist(err) {
+		t.Errorf("expected no state file to be written, got %v", err)
+	}
+	if err := st.Write(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// entering a tree pushes its selection
+	if err := st.Chdir(ctx, "/src/app/.kcn", "delta-prod", "app-x"); err != nil {
+		t.Fatal(err)
+	}
+	expect("delta-prod", "app-x")
+
+	// moving within the tree changes nothing
+	if err := st.Chdir(ctx, "/src/app/.kcn", "delta-prod", "app-x"); err != nil {
+		t.Fatal(err)
+	}
+	if st.Stack.Length() != 2 {
+		t.Errorf("expected 2 elements, got %d", st.Stack.Length())
+	}
+
+	// moving to another tree replaces the selection
+	if err := st.Chdir(ctx, "/src/other/.kcn", "bravo-stage"); err != nil {
+		t.Fatal(err)
+	}
+	expect("bravo-stage", kubectl.DefaultNamespace)
+	if st.Stack.Length() != 2 {
+		t.Errorf("expected 2 elements, got %d", st.Stack.Length())
+	}
+
+	// leaving restores the prior selection
+	if err := st.Chdir(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	expect("alpha-dev", "app-a")
+	if st.Dir != nil {
+		t.Error("leaving a tree should forget it")
+	}
+
+	// a manual switch within a tree is kept when leaving
+	if err := st.Chdir(ctx, "/src/app/.kcn", "delta-prod", "app-x"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Update(ctx, ".", "app-y"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Chdir(ctx, ""); err != nil {
+		t.Fatal(err)
+	}
+	expect("delta-prod", "app-y")
+}
+
+func TestUpdateHistory(t *testing.T) {
+	clock := time.Unix(1600000000, 0)
+	now = func() time.Time {
+		clock = clock.Add(time.Minute)
+		return clock
+	}
+	defer func() { now = time.Now }()
+
+	st := stateFixture(t)
+	st.Config.History.MaxDepth = 3
+
+	for _, v := range []string{"alpha-dev", "bravo-stage", "delta-prod", "bravo-stage"} {
+		if err := st.Update(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the oldest element is evicted
+	var contexts []string
+	for _, v := range st.Stack.Elements() {
+		contexts = append(contexts, v.Context)
+	}
+	expected := []string{"bravo-stage", "delta-prod", "bravo-stage"}
+	if !reflect.DeepEqual(contexts, expected) {
+		t.Fatalf("expected %v, got %v", expected, contexts)
+	}
+
+	if err := st.Update(ctx, "@3"); err == nil {
+		t.Error("jump beyond history should fail")
+	}
+	if err := st.Update(ctx, "@1", "app-x"); err == nil {
+		t.Error("jump with a namespace should fail")
+	}
+
+	if err := st.Update(ctx, "@2"); err != nil {
+		t.Fatal(err)
+	}
+
+	curr, err := st.Stack.Peek()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curr.Context != "bravo-stage" || st.Stack.Length() != 3 {
+		t.Errorf("jump should raise @2 to the top, got %+v", st.Stack.Elements())
+	}
+	if curr.Timestamp() != clock {
+		t.Errorf("jump should record the time, got %s", curr.Timestamp())
+	}
+
+	prev, err := st.Stack.PeekPrev()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev.Timestamp() != time.Unix(1600000000, 0).Add(4*time.Minute) {
+		t.Errorf("unexpected time for previous element %s", prev.Timestamp())
+	}
+}
+
+func TestPop(t *testing.T) {
+	st := stateFixture(t)
+	st.Config.Protected = []string{"*-prod"}
+
+	for _, v := range [][]string{{"delta-prod"}, {"alpha-dev", "app-b"}, {"bravo-stage"}} {
+		st.Confirm = func(string) bool { return true }
+		if err := st.Update(ctx, v...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := st.Pop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	curr, err := st.Stack.Peek()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curr.Context != "alpha-dev" || curr.Namespace != "app-b" || st.Stack.Length() != 2 {
+		t.Errorf("pop should return to the previous element, got %+v", st.Stack.Elements())
+	}
+
+	// returning into a protected context must be confirmed
+	st.Confirm = func(string) bool { return false }
+	if err := st.Pop(ctx); err == nil {
+		t.Error("pop into a protected context should be refused")
+	}
+	if st.Stack.Length() != 2 {
+		t.Errorf("refused pop should keep the stack, got %+v", st.Stack.Elements())
+	}
+
+	st.Confirm = func(string) bool { return true }
+	for i := 0; i < 2; i++ {
+		if err := st.Pop(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.Pop(ctx); err == nil {
+		t.Error("pop of an empty stack should fail")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	st := stateFixture(t)
+	st.Config.Aliases = map[string]config.Alias{
+		"prod": {Context: "delta-prod", Namespace: "app-x"},
+	}
+
+	for _, v := range [][]string{{"alpha-dev", "app-b"}, {"bravo-stage"}} {
+		if err := st.Update(ctx, v...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before := st.Stack.Elements()
+
+	tests := []struct {
+		args     []string
+		expected Element
+	}{
+		{[]string{"prod"}, Element{Context: "delta-prod", Namespace: "app-x",
+			Alias: "prod", NamespaceFrom: FromAlias}},
+		{[]string{"alpha", "app-c"}, Element{Context: "alpha-dev", Namespace: "app-c",
+			NamespaceFrom: FromArgument}},
+		{[]string{".", "app-e"}, Element{Context: "bravo-stage", Namespace: "app-e",
+			NamespaceFrom: FromArgument}},
+		{[]string{"delta"}, Element{Context: "delta-prod", Namespace: "app-y",
+			NamespaceFrom: FromKubeconfig}},
+		{[]string{"-"}, before[1]},
+		{[]string{"@1"}, before[1]},
+	}
+
+	for _, test := range tests {
+		e, err := st.Resolve(ctx, test.args...)
+		if err != nil {
+			t.Errorf("%v: %s", test.args, err)
+			continue
+		}
+		if *e != test.expected {
+			t.Errorf("%v: expected %+v, got %+v", test.args, test.expected, *e)
+		}
+	}
+
+	for _, args := range [][]string{{"nope"}, {"@2"}, {"alpha", "app-x"}} {
+		if _, err := st.Resolve(ctx, args...); err == nil {
+			t.Errorf("%v should fail", args)
+		}
+	}
+
+	if !reflect.DeepEqual(st.Stack.Elements(), before) {
+		t.Errorf("resolve should leave the stack alone, got %+v", st.Stack.Elements())
+	}
+}
+
+func TestAudit(t *testing.T) {
+	st := stateFixture(t)
+	st.Audit = audit.NewLog(filepath.Join(filepath.Dir(st.path), "audit.jsonl"), 0, 0)
+	st.Config.Aliases = map[string]config.Alias{
+		"dev": {Context: "alpha-dev", Namespace: "app-b"},
+	}
+
+	for _, v := range [][]string{{"dev"}, {"bravo", "app-d"}, {"-"}, {"@1"}} {
+		if err := st.Update(ctx, v...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// refused, and not recorded
+	if err := st.Update(ctx, "nope"); err == nil {
+		t.Fatal("unknown context should fail")
+	}
+	if err := st.Pop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Clear(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := st.Audit.Read(audit.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev := &audit.Selection{Context: "alpha-dev", Namespace: "app-b",
+		NamespaceFrom: FromAlias, Alias: "dev"}
+	stage := &audit.Selection{Context: "bravo-stage", Namespace: "app-d",
+		NamespaceFrom: FromArgument}
+	expected := []struct {
+		action            string
+		previous, current *audit.Selection
+	}{
+		{audit.ActionSwitch, nil, dev},
+		{audit.ActionSwitch, dev, stage},
+		{audit.ActionSwap, stage, dev},
+		{audit.ActionJump, dev, stage},
+		{audit.ActionPop, stage, dev},
+		{audit.ActionClear, dev, nil},
+	}
+
+	if len(entries) != len(expected) {
+		t.Fatalf("expected %d entries, got %+v", len(expected), entries)
+	}
+	for i, e := range entries {
+		if e.Action != expected[i].action ||
+			!reflect.DeepEqual(e.Previous, expected[i].previous) ||
+			!reflect.DeepEqual(e.Current, expected[i].current) {
+			t.Errorf("entry %d: expected %+v, got %s %+v -> %+v",
+				i, expected[i], e.Action, e.Previous, e.Current)
+		}
+		if e.Session != "state" {
+			t.Errorf("entry %d: expected the session of the state file, got %s", i, e.Session)
+		}
+	}
+}
+
+func TestByLabels(t *testing.T) {
+	st := stateFixture(t)
+	st.Config.Contexts = []config.Context{
+		{Name: "alpha-dev", Labels: map[string]string{"env": "dev"}},
+		{Name: "bravo-stage", Labels: map[string]string{"env": "stage"}},
+		{Name: "/-(stage|prod)$/", Labels: map[string]string{"region": "eu"}},
+		{Name: "delta-prod", Labels: map[string]string{"env": "prod"}},
+	}
+
+	tests := []struct {
+		selector string
+		expected string
+	}{
+		{"env=stage", "bravo-stage"},
+		{"region=eu,env!=stage", "delta-prod"},
+		{"!region", "alpha-dev"},
+	}
+
+	for _, test := range tests {
+		sel, err := labels.Parse(test.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		context, err := st.ByLabels(ctx, sel)
+		if err != nil {
+			t.Errorf("%s: %s", test.selector, err)
+		} else if context != test.expected {
+			t.Errorf("%s: expected %s, got %s", test.selector, test.expected, context)
+		}
+	}
+
+	for _, selector := range []string{"region=eu", "env=qa"} {
+		sel, _ := labels.Parse(selector)
+		if context, err := st.ByLabels(ctx, sel); err == nil {
+			t.Errorf("%s should fail, got %s", selector, context)
+		}
+	}
+}
+
+func TestUpdateDefaultNamespace(t *testing.T) {
+	contexts := []config.Context{
+		{Name: "alpha-dev", Namespace: "app-c"},
+		{Name: "/-prod$/", Namespace: "app-z"},
+		{Name: "*", Namespace: "app-f"},
+	}
+
+	tests := []struct {
+		name     string
+		session  [][]string
+		recorded [][]string
+		contexts []config.Context
+		args     []string
+		expected string
+		from     string
+	}{
+		{"explicit", [][]string{{"alpha-dev", "app-a"}}, nil, contexts,
+			[]string{"alpha-dev", "app-b"}, "app-b", FromArgument},
+		{"session", [][]string{{"alpha-dev", "app-a"}, {"bravo-stage"}}, nil, contexts,
+			[]string{"alpha-dev"}, "app-a", FromSession},
+		{"session over recent", [][]string{{"alpha-dev", "app-a"}, {"bravo-stage"}},
+			[][]string{{"alpha-dev", "app-b"}}, contexts,
+			[]string{"alpha-dev"}, "app-a", FromSession},
+		{"recent", nil, [][]string{{"delta-prod", "app-x"}, {"delta-prod", "app-z"}}, contexts,
+			[]string{"delta-prod"}, "app-z", FromRecent},
+		{"kubeconfig", nil, nil, contexts,
+			[]string{"delta-prod"}, "app-y", FromKubeconfig},
+		{"config", nil, nil, contexts,
+			[]string{"alpha-dev"}, "app-c", FromConfig},
+		{"config pattern", nil, nil, contexts,
+			[]string{"bravo-stage"}, "app-f", FromConfig},
+		{"default", nil, nil, nil,
+			[]string{"alpha-dev"}, kubectl.DefaultNamespace, FromDefault},
+	}
+
+	for _, test := range tests {
+		st := stateFixture(t)
+		st.Config.Contexts = test.contexts
+
+		// recorded by another session
+		other := recent.NewStore(filepath.Join(filepath.Dir(st.Path()), "recent.json"))
+		for _, v := range test.recorded {
+			if err := other.Record(v[0], v[1]); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		for _, v := range test.session {
+			if err := st.Update(ctx, v...); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		st.Recent = other
+		if err := st.Update(ctx, test.args...); err != nil {
+			t.Errorf("%s: %s", test.name, err)
+			continue
+		}
+
+		curr, err := st.Stack.Peek()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if curr.Namespace != test.expected || curr.NamespaceFrom != test.from {
+			t.Errorf("%s: expected %s from %s, got %s from %s", test.name,
+				test.expected, test.from, curr.Namespace, curr.NamespaceFrom)
+		}
+
+		if r, err := other.Load(); err != nil || r.Namespace(curr.Context) != curr.Namespace {
+			t.Errorf("%s: expected %s to be recorded", test.name, curr.Namespace)
+		}
+	}
+}
+
+// unreachable fails namespace lookups, as a cluster that is down or behind a
+// VPN would
+type unreachable struct {
+	kubectl.Kubectl
+}
+
+func (unreachable) GetNamespaceList(ctx context.Context, context string) ([]string, error) {
+	return nil, errors.New("timed out")
+}
+
+func TestUpdateUnreachable(t *testing.T) {
+	st := stateFixture(t)
+	st.k = unreachable{st.k}
+
+	if err := st.Update(ctx, "delta", "app-nope"); err != nil {
+		t.Fatal(err)
+	}
+
+	curr, err := st.Stack.Peek()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if curr.Context != "delta-prod" || curr.Namespace != "app-nope" {
+		t.Errorf("expected the namespace to be used unchecked, got %+v", curr)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := st.Update(canceled, "alpha", "app-a"); err != context.Canceled {
+		t.Errorf("expected a canceled update to fail, got %v", err)
+	}
+}
+
+func TestWriteKubeconfig(t *testing.T) {
+	st := stateFixture(t)
+
+	for _, v := range [][]string{{"bravo-stage", "app-d"}, {"delta-prod", "app-x"}} {
+		if err := st.Update(ctx, v...); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the session's kubeconfig selects the top of the stack
+	c, err := kubeconfig.Load(st.KubeconfigPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.CurrentContext != "delta-prod" {
+		t.Errorf("expected current-context delta-prod, got %q", c.CurrentContext)
+	}
+	expected := []kubeconfig.NamedContext{{
+		Name: "delta-prod",
+		Context: kubeconfig.Context{
+			Cluster:   "delta-prod",
+			User:      "delta-prod",
+			Namespace: "app-x",
+		},
+	}}
+	if !reflect.DeepEqual(c.Contexts, expected) {
+		t.Errorf("expected contexts %+v, got %+v", expected, c.Contexts)
+	}
+
+	path := filepath.Join(filepath.Dir(st.KubeconfigPath()), "empty")
+	if err := st.WriteKubeconfig(ctx, path, nil); err != nil {
+		t.Fatal(err)
+	}
+	c, err = kubeconfig.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(c.CurrentContext) > 0 || len(c.Contexts) > 0 {
+		t.Errorf("expected nothing selected, got %+v", c)
+	}
+}
+
 // kcn - when last context is empty
 // kcn . - when last namespace is empty
 // kcn . - when last namespace doesn't exist in current context